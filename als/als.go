@@ -0,0 +1,317 @@
+// Package als trains an implicit feedback matrix factorization model with
+// alternating least squares on the purchases in the graph.
+package als
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// ErrStale is returned when a model was trained on other data than the store holds now
+var ErrStale = errors.New("als: model is stale, train it again")
+
+// Config holds the training parameters
+type Config struct {
+	// Factors is the number of latent factors, defaults to 10
+	Factors int
+	// Iterations is the number of alternating passes, defaults to 10
+	Iterations int
+	// Lambda is the regularization, defaults to 0.1
+	Lambda float64
+	// Alpha scales the confidence of a purchase count r to 1 + Alpha r, defaults to 40
+	Alpha float64
+	// Seed seeds the initial factors
+	Seed int64
+}
+
+// Model holds the learned factors of the customers and products, by id
+type Model struct {
+	Factors int `json:"factors"`
+	// Purchases is the number of purchases the model was trained on and Fingerprint
+	// a hash of their customers and products
+	Purchases   int                  `json:"purchases"`
+	Fingerprint string               `json:"fingerprint"`
+	TrainedAt   time.Time            `json:"trained_at"`
+	Users       map[string][]float64 `json:"users"`
+	Items       map[string][]float64 `json:"items"`
+}
+
+// ModelFile returns the file the model of a database file is saved to
+func ModelFile(dbFile string) string {
+	return dbFile + ".als.json"
+}
+
+// Train exports the client -has_purchase-> purchase -purchase_of-> product edges as an implicit
+// feedback matrix, with the number of purchases as the feedback, and factorizes it
+func Train(ctx context.Context, store *cayley.Handle, cfg Config) (*Model, error) {
+	if cfg.Factors <= 0 {
+		cfg.Factors = 10
+	}
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = 10
+	}
+	if cfg.Lambda <= 0 {
+		cfg.Lambda = 0.1
+	}
+	if cfg.Alpha <= 0 {
+		cfg.Alpha = 40
+	}
+
+	rows, err := purchaseRows(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// purchase counts per customer and product
+	counts := make(map[string]map[string]float64)
+	items := make(map[string]bool)
+	for _, r := range rows {
+		customer, product := r[0], r[1]
+		if counts[customer] == nil {
+			counts[customer] = make(map[string]float64)
+		}
+		counts[customer][product]++
+		items[product] = true
+	}
+
+	// sorted ids keep training deterministic for a seed
+	userIDs := make([]string, 0, len(counts))
+	for u := range counts {
+		userIDs = append(userIDs, u)
+	}
+	sort.Strings(userIDs)
+	itemIDs := make([]string, 0, len(items))
+	for i := range items {
+		itemIDs = append(itemIDs, i)
+	}
+	sort.Strings(itemIDs)
+
+	// the same feedback seen from the products
+	byItem := make(map[string]map[string]float64)
+	for u, row := range counts {
+		for i, r := range row {
+			if byItem[i] == nil {
+				byItem[i] = make(map[string]float64)
+			}
+			byItem[i][u] = r
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	m := &Model{
+		Factors:     cfg.Factors,
+		Purchases:   len(rows),
+		Fingerprint: fingerprint(rows),
+		TrainedAt:   time.Now(),
+		Users:       initFactors(userIDs, cfg.Factors, rnd),
+		Items:       initFactors(itemIDs, cfg.Factors, rnd),
+	}
+
+	for it := 0; it < cfg.Iterations; it++ {
+		solve(m.Users, userIDs, m.Items, counts, cfg)
+		solve(m.Items, itemIDs, m.Users, byItem, cfg)
+	}
+	return m, nil
+}
+
+// Stale reports whether the purchases in the store changed since the model was trained,
+// other changes like inventory updates or mined bundles leave the model fresh
+func (m *Model) Stale(ctx context.Context, store *cayley.Handle) (bool, error) {
+	rows, err := purchaseRows(ctx, store)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) != m.Purchases || fingerprint(rows) != m.Fingerprint, nil
+}
+
+// purchaseRows returns the customer and product of every purchase in the store
+func purchaseRows(ctx context.Context, store *cayley.Handle) ([][2]string, error) {
+	var rows [][2]string
+	p := cayley.StartPath(store).Tag("customer").Out(vocab.HasPurchase).Out(vocab.PurchaseOf).Tag("product")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		rows = append(rows, [2]string{vocab.String(m["customer"]), vocab.String(m["product"])})
+	})
+	return rows, err
+}
+
+// fingerprint hashes the customers and products of the purchases, in any order
+func fingerprint(rows [][2]string) string {
+	lines := make([]string, len(rows))
+	for i, r := range rows {
+		lines[i] = r[0] + "\t" + r[1]
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, l := range lines {
+		io.WriteString(h, l+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Scores returns the predicted preference of the customer for every product, or nil
+// when the customer was not part of the training data
+func (m *Model) Scores(customer string) map[string]float64 {
+	u, ok := m.Users[customer]
+	if !ok {
+		return nil
+	}
+	scores := make(map[string]float64, len(m.Items))
+	for id, v := range m.Items {
+		scores[id] = dot(u, v)
+	}
+	return scores
+}
+
+// Save writes the model as JSON
+func (m *Model) Save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads a model written by Save
+func Load(file string) (*Model, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Model{}
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func initFactors(ids []string, k int, rnd *rand.Rand) map[string][]float64 {
+	factors := make(map[string][]float64, len(ids))
+	for _, id := range ids {
+		v := make([]float64, k)
+		for f := range v {
+			v[f] = rnd.Float64() * 0.01
+		}
+		factors[id] = v
+	}
+	return factors
+}
+
+// solve updates the factors x of every id with the other side y fixed:
+//
+//	x = (YᵀY + Yᵀ(C - I)Y + λI)⁻¹ YᵀCp
+//
+// where C holds the confidence 1 + αr of the feedback and p is 1 where there is feedback
+func solve(x map[string][]float64, ids []string, y map[string][]float64, feedback map[string]map[string]float64, cfg Config) {
+	k := cfg.Factors
+
+	// YᵀY is the same for all ids
+	yty := make([][]float64, k)
+	for a := range yty {
+		yty[a] = make([]float64, k)
+	}
+	for _, v := range y {
+		for a := 0; a < k; a++ {
+			for b := 0; b < k; b++ {
+				yty[a][b] += v[a] * v[b]
+			}
+		}
+	}
+
+	for _, id := range ids {
+		A := make([][]float64, k)
+		for a := range A {
+			A[a] = make([]float64, k)
+			copy(A[a], yty[a])
+			A[a][a] += cfg.Lambda
+		}
+		b := make([]float64, k)
+
+		for other, r := range feedback[id] {
+			v, ok := y[other]
+			if !ok {
+				continue
+			}
+			c := 1 + cfg.Alpha*r
+			for a := 0; a < k; a++ {
+				for bb := 0; bb < k; bb++ {
+					A[a][bb] += (c - 1) * v[a] * v[bb]
+				}
+				b[a] += c * v[a]
+			}
+		}
+
+		x[id] = solveLinear(A, b)
+	}
+}
+
+// solveLinear solves Ax = b with Gaussian elimination and partial pivoting
+func solveLinear(A [][]float64, b []float64) []float64 {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if abs(A[row][col]) > abs(A[pivot][col]) {
+				pivot = row
+			}
+		}
+		A[col], A[pivot] = A[pivot], A[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		if A[col][col] == 0 {
+			continue
+		}
+		for row := col + 1; row < n; row++ {
+			f := A[row][col] / A[col][col]
+			for c := col; c < n; c++ {
+				A[row][c] -= f * A[col][c]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		s := b[row]
+		for c := row + 1; c < n; c++ {
+			s -= A[row][c] * x[c]
+		}
+		if A[row][row] != 0 {
+			x[row] = s / A[row][row]
+		}
+	}
+	return x
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,185 @@
+package als
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// purchases are two groups of customers with their own products, and a customer
+// who only bought one product of the first group
+var purchases = map[string][]string{
+	"ann":  {"walkman", "discman"},
+	"bob":  {"walkman", "discman"},
+	"cat":  {"pillow", "blanket"},
+	"dave": {"pillow", "blanket"},
+	"eve":  {"walkman"},
+}
+
+func purchaseStore(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var quads []quad.Quad
+	for customer, products := range purchases {
+		for _, product := range products {
+			purchase := quad.IRI(fmt.Sprintf("%s-%s", customer, product))
+			quads = append(quads,
+				quad.Make(quad.IRI(customer), vocab.HasPurchase, purchase, nil),
+				quad.Make(purchase, vocab.PurchaseOf, quad.IRI(product), nil),
+			)
+		}
+	}
+	if err := store.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestTrain(t *testing.T) {
+	store := purchaseStore(t)
+	m, err := Train(context.TODO(), store, Config{Factors: 2, Iterations: 15, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Users) != len(purchases) || len(m.Items) != 4 {
+		t.Fatalf("got %d users and %d items, want %d and 4", len(m.Users), len(m.Items), len(purchases))
+	}
+
+	for customer, bought := range purchases {
+		scores := m.Scores(customer)
+		held := make(map[string]bool)
+		for _, product := range bought {
+			held[product] = true
+		}
+		for _, product := range bought {
+			for other, s := range scores {
+				if !held[other] && scores[product] <= s {
+					t.Errorf("%s: bought %s scores %.3f, not above unseen %s with %.3f", customer, product, scores[product], other, s)
+				}
+			}
+		}
+	}
+
+	// eve bought a walkman like ann and bob, who also bought a discman
+	eve := m.Scores("eve")
+	if eve["discman"] <= eve["pillow"] || eve["discman"] <= eve["blanket"] {
+		t.Errorf("eve: discman %.3f should score above pillow %.3f and blanket %.3f", eve["discman"], eve["pillow"], eve["blanket"])
+	}
+
+	if m.Scores("nobody") != nil {
+		t.Error("expected no scores for an unknown customer")
+	}
+}
+
+func TestStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		add    []quad.Quad
+		remove []quad.Quad
+		stale  bool
+	}{
+		{"fresh", nil, nil, false},
+		{"inventory and bundles", []quad.Quad{
+			quad.Make(quad.IRI("walkman"), vocab.Stock, 3, "inventory"),
+			quad.Make(quad.IRI("bundle/walkman+discman"), quad.IRI("bundle_item"), quad.IRI("walkman"), "bundles"),
+		}, nil, false},
+		{"new purchase", []quad.Quad{
+			quad.Make(quad.IRI("eve"), vocab.HasPurchase, quad.IRI("eve-pillow"), nil),
+			quad.Make(quad.IRI("eve-pillow"), vocab.PurchaseOf, quad.IRI("pillow"), nil),
+		}, nil, true},
+		// as many quads as before, but eve bought a pillow instead of a walkman
+		{"changed purchase", []quad.Quad{
+			quad.Make(quad.IRI("eve-walkman"), vocab.PurchaseOf, quad.IRI("pillow"), nil),
+		}, []quad.Quad{
+			quad.Make(quad.IRI("eve-walkman"), vocab.PurchaseOf, quad.IRI("walkman"), nil),
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := purchaseStore(t)
+			m, err := Train(context.TODO(), store, Config{Factors: 2, Iterations: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, q := range tt.remove {
+				if err := store.RemoveQuad(q); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.AddQuadSet(tt.add); err != nil {
+				t.Fatal(err)
+			}
+
+			stale, err := m.Stale(context.TODO(), store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stale != tt.stale {
+				t.Errorf("stale %v, want %v", stale, tt.stale)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "als")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := Train(context.TODO(), purchaseStore(t), Config{Factors: 2, Iterations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := ModelFile(filepath.Join(dir, "demo.db"))
+	if err := m.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Factors != m.Factors || loaded.Purchases != m.Purchases || loaded.Fingerprint != m.Fingerprint || !loaded.TrainedAt.Equal(m.TrainedAt) {
+		t.Errorf("loaded %d factors, %d purchases (%s) at %v, want %d, %d (%s) at %v",
+			loaded.Factors, loaded.Purchases, loaded.Fingerprint, loaded.TrainedAt, m.Factors, m.Purchases, m.Fingerprint, m.TrainedAt)
+	}
+	if !reflect.DeepEqual(loaded.Users, m.Users) || !reflect.DeepEqual(loaded.Items, m.Items) {
+		t.Error("loaded factors differ from the saved ones")
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error loading a missing file")
+	}
+}
+
+func TestSolveLinear(t *testing.T) {
+	// the first pivot is zero, so the rows have to be swapped
+	A := [][]float64{
+		{0, 2, 1},
+		{1, 1, 1},
+		{2, 1, 3},
+	}
+	b := []float64{7, 6, 13}
+	want := []float64{1, 2, 3}
+
+	x := solveLinear(A, b)
+	for i := range want {
+		if math.Abs(x[i]-want[i]) > 1e-9 {
+			t.Fatalf("x = %v, want %v", x, want)
+		}
+	}
+}
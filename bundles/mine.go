@@ -0,0 +1,195 @@
+// Package bundles mines products that are frequently bought together from the
+// purchases in the graph, and stores them as bundle nodes.
+package bundles
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Config holds the thresholds of the mining job
+type Config struct {
+	// MinSupport is the minimal fraction of customers that bought all products of a bundle
+	MinSupport float64
+	// MinConfidence is the minimal confidence of the best rule of a bundle
+	MinConfidence float64
+	// MinLift is the minimal lift of the best rule of a bundle
+	MinLift float64
+	// MaxSize is the maximal number of products in a bundle, defaults to 3
+	MaxSize int
+}
+
+// itemset is a sorted set of product ids
+type itemset []string
+
+func (s itemset) key() string {
+	return strings.Join(s, "\x00")
+}
+
+// Mine finds bundles with the Apriori algorithm. Every customer's purchases form one
+// transaction. For every frequent itemset the rules {others} -> product are checked and
+// the itemset becomes a bundle when its best rule meets the confidence and lift thresholds.
+func Mine(ctx context.Context, store *cayley.Handle, cfg Config) ([]Bundle, error) {
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = 3
+	}
+
+	baskets := make(map[string]map[string]struct{})
+	p := cayley.StartPath(store).Tag("customer").Out(vocab.HasPurchase).Out(vocab.PurchaseOf).Tag("product")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		customer := m["customer"].String()
+		if baskets[customer] == nil {
+			baskets[customer] = make(map[string]struct{})
+		}
+		if product, ok := m["product"].(quad.IRI); ok {
+			baskets[customer][string(product)] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]itemset, 0, len(baskets))
+	for _, b := range baskets {
+		t := make(itemset, 0, len(b))
+		for product := range b {
+			t = append(t, product)
+		}
+		sort.Strings(t)
+		transactions = append(transactions, t)
+	}
+	n := float64(len(transactions))
+	if n == 0 {
+		return nil, nil
+	}
+
+	// support of every frequent itemset, by key
+	support := make(map[string]float64)
+
+	// frequent single products
+	counts := make(map[string]int)
+	for _, t := range transactions {
+		for _, product := range t {
+			counts[product]++
+		}
+	}
+	var frequent []itemset
+	for product, c := range counts {
+		if s := float64(c) / n; s >= cfg.MinSupport {
+			support[product] = s
+			frequent = append(frequent, itemset{product})
+		}
+	}
+
+	var bundles []Bundle
+	for size := 2; size <= cfg.MaxSize && len(frequent) > 0; size++ {
+		candidates := join(frequent, support)
+
+		counts := make(map[string]int, len(candidates))
+		for _, t := range transactions {
+			for _, c := range candidates {
+				if contains(t, c) {
+					counts[c.key()]++
+				}
+			}
+		}
+
+		frequent = frequent[:0]
+		for _, c := range candidates {
+			s := float64(counts[c.key()]) / n
+			if s < cfg.MinSupport || s == 0 {
+				continue
+			}
+			support[c.key()] = s
+			frequent = append(frequent, c)
+
+			// rules: all but one product -> the remaining product
+			b := Bundle{Support: s}
+			for i, product := range c {
+				rest := make(itemset, 0, len(c)-1)
+				rest = append(rest, c[:i]...)
+				rest = append(rest, c[i+1:]...)
+
+				confidence := s / support[rest.key()]
+				lift := confidence / support[product]
+				if confidence > b.Confidence || (confidence == b.Confidence && lift > b.Lift) {
+					b.Confidence, b.Lift = confidence, lift
+				}
+			}
+			if b.Confidence < cfg.MinConfidence || b.Lift < cfg.MinLift {
+				continue
+			}
+
+			for _, product := range c {
+				b.Products = append(b.Products, quad.IRI(product))
+			}
+			b.ID = bundleID(c)
+			bundles = append(bundles, b)
+		}
+	}
+
+	sort.Slice(bundles, func(i, j int) bool {
+		if bundles[i].Lift != bundles[j].Lift {
+			return bundles[i].Lift > bundles[j].Lift
+		}
+		return bundles[i].ID < bundles[j].ID
+	})
+	return bundles, nil
+}
+
+// join builds the candidate itemsets one product larger than the frequent ones, keeping
+// only the candidates of which every subset is frequent
+func join(frequent []itemset, support map[string]float64) []itemset {
+	sort.Slice(frequent, func(i, j int) bool { return frequent[i].key() < frequent[j].key() })
+
+	var candidates []itemset
+	for i := range frequent {
+		for j := i + 1; j < len(frequent); j++ {
+			a, b := frequent[i], frequent[j]
+			k := len(a) - 1
+			if itemset(a[:k]).key() != itemset(b[:k]).key() {
+				break
+			}
+
+			c := make(itemset, 0, len(a)+1)
+			c = append(c, a...)
+			c = append(c, b[k])
+
+			pruned := false
+			for skip := range c {
+				subset := make(itemset, 0, len(c)-1)
+				subset = append(subset, c[:skip]...)
+				subset = append(subset, c[skip+1:]...)
+				if _, ok := support[subset.key()]; !ok {
+					pruned = true
+					break
+				}
+			}
+			if !pruned {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// contains reports whether the sorted transaction holds all products of the sorted itemset
+func contains(t, s itemset) bool {
+	i := 0
+	for _, product := range t {
+		if i < len(s) && product == s[i] {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// bundleID returns the id of the bundle of the given products, the same products always give the same id
+func bundleID(s itemset) quad.IRI {
+	return quad.IRI("bundle/" + strings.Join(s, "+"))
+}
@@ -0,0 +1,167 @@
+package bundles
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// transactions of the tests, the products a customer bought
+var transactions = [][]string{
+	{"a", "b", "c"},
+	{"a", "b"},
+	{"a", "c"},
+	{"b", "d"},
+}
+
+func transactionStore(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var quads []quad.Quad
+	for i, products := range transactions {
+		customer := quad.IRI(fmt.Sprintf("customer%d", i))
+		for _, product := range products {
+			purchase := quad.IRI(fmt.Sprintf("purchase%d-%s", i, product))
+			quads = append(quads,
+				quad.Make(customer, vocab.HasPurchase, purchase, nil),
+				quad.Make(purchase, vocab.PurchaseOf, quad.IRI(product), nil),
+			)
+		}
+	}
+	if err := store.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestMine(t *testing.T) {
+	store := transactionStore(t)
+
+	// singles: a 3/4, b 3/4, c 2/4, d 1/4
+	ab := Bundle{ID: "bundle/a+b", Support: 0.5, Confidence: 2.0 / 3, Lift: (2.0 / 3) / 0.75}
+	ac := Bundle{ID: "bundle/a+c", Support: 0.5, Confidence: 1, Lift: 1 / 0.75}
+	bc := Bundle{ID: "bundle/b+c", Support: 0.25, Confidence: 0.5, Lift: 0.5 / 0.75}
+	bd := Bundle{ID: "bundle/b+d", Support: 0.25, Confidence: 1, Lift: 1 / 0.75}
+	abc := Bundle{ID: "bundle/a+b+c", Support: 0.25, Confidence: 1, Lift: 1 / 0.75}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []Bundle
+	}{
+		// bc is not frequent, so abc is pruned
+		{"support", Config{MinSupport: 0.5}, []Bundle{ac, ab}},
+		{"confidence", Config{MinSupport: 0.5, MinConfidence: 0.8}, []Bundle{ac}},
+		{"lift", Config{MinSupport: 0.5, MinLift: 1}, []Bundle{ac}},
+		// equal lifts are ordered by id
+		{"low support", Config{MinSupport: 0.25}, []Bundle{abc, ac, bd, ab, bc}},
+		{"pairs only", Config{MinSupport: 0.25, MaxSize: 2}, []Bundle{ac, bd, ab, bc}},
+		{"nothing frequent", Config{MinSupport: 0.8}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Mine(context.TODO(), store, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d bundles %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, b := range got {
+				w := tt.want[i]
+				if b.ID != w.ID {
+					t.Errorf("bundle %d is %s, want %s", i, b.ID, w.ID)
+					continue
+				}
+				if !reflect.DeepEqual(b.Products, products(w.ID)) {
+					t.Errorf("%s has products %v", b.ID, b.Products)
+				}
+				if !near(b.Support, w.Support) || !near(b.Confidence, w.Confidence) || !near(b.Lift, w.Lift) {
+					t.Errorf("%s support %v confidence %v lift %v, want %v %v %v",
+						b.ID, b.Support, b.Confidence, b.Lift, w.Support, w.Confidence, w.Lift)
+				}
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	supported := func(sets ...itemset) map[string]float64 {
+		support := make(map[string]float64)
+		for _, s := range sets {
+			support[s.key()] = 1
+		}
+		return support
+	}
+
+	tests := []struct {
+		name     string
+		frequent []itemset
+		want     []itemset
+	}{
+		{
+			"pairs from singles",
+			[]itemset{{"c"}, {"a"}, {"b"}},
+			[]itemset{{"a", "b"}, {"a", "c"}, {"b", "c"}},
+		},
+		{
+			// bcd is pruned because cd is not frequent
+			"triples with a shared prefix",
+			[]itemset{{"a", "b"}, {"a", "c"}, {"b", "c"}, {"b", "d"}},
+			[]itemset{{"a", "b", "c"}},
+		},
+		{
+			"no shared prefix",
+			[]itemset{{"a", "b"}, {"c", "d"}},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := join(tt.frequent, supported(tt.frequent...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("join(%v) = %v, want %v", tt.frequent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		t, s itemset
+		want bool
+	}{
+		{itemset{"a", "b", "c"}, itemset{"a", "c"}, true},
+		{itemset{"a", "b", "c"}, itemset{"a", "b", "c"}, true},
+		{itemset{"a", "b", "c"}, itemset{}, true},
+		{itemset{"a", "c"}, itemset{"a", "b"}, false},
+		{itemset{"b"}, itemset{"a", "b"}, false},
+	}
+	for _, tt := range tests {
+		if got := contains(tt.t, tt.s); got != tt.want {
+			t.Errorf("contains(%v, %v) = %v, want %v", tt.t, tt.s, got, tt.want)
+		}
+	}
+}
+
+// products returns the products in the id of a bundle
+func products(id quad.IRI) []quad.IRI {
+	var list []quad.IRI
+	for _, p := range strings.Split(strings.TrimPrefix(string(id), "bundle/"), "+") {
+		list = append(list, quad.IRI(p))
+	}
+	return list
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
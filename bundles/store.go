@@ -0,0 +1,145 @@
+package bundles
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Label is the label bundles are stored under
+const Label = "bundles"
+
+var (
+	predBundleItem = quad.IRI("bundle_item")
+	classBundle    = quad.IRI("bundle")
+)
+
+// Bundle is a set of products that are frequently bought together
+type Bundle struct {
+	rdfType    struct{}   `quad:"type > bundle"`
+	ID         quad.IRI   `quad:"@id" json:"id"`
+	Products   []quad.IRI `quad:"bundle_item" json:"products"`
+	Support    float64    `quad:"support" json:"support"`
+	Confidence float64    `quad:"confidence" json:"confidence"`
+	Lift       float64    `quad:"lift" json:"lift"`
+}
+
+// meta describes the bundle class
+var meta = []quad.Quad{
+	quad.Make(classBundle, vocab.Type, vocab.ClassClass, Label),
+	quad.Make(classBundle, vocab.Label, "Bundle", Label),
+	quad.Make(classBundle, vocab.Desc, "Products that are frequently bought together", Label),
+	quad.Make(classBundle, vocab.HasProperty, predBundleItem, Label),
+	quad.Make(classBundle, vocab.HasProperty, quad.IRI("support"), Label),
+	quad.Make(classBundle, vocab.HasProperty, quad.IRI("confidence"), Label),
+	quad.Make(classBundle, vocab.HasProperty, quad.IRI("lift"), Label),
+	quad.Make(predBundleItem, vocab.Range, quad.IRI("iri"), Label),
+	quad.Make(quad.IRI("support"), vocab.Range, quad.IRI("float"), Label),
+	quad.Make(quad.IRI("confidence"), vocab.Range, quad.IRI("float"), Label),
+	quad.Make(quad.IRI("lift"), vocab.Range, quad.IRI("float"), Label),
+}
+
+// Replace removes all stored bundles and writes the given ones in one transaction,
+// together with the metadata of the bundle class when it is not stored yet
+func Replace(ctx context.Context, store *cayley.Handle, bundles []Bundle) error {
+	tx := graph.NewTransaction()
+
+	old, err := cayley.StartPath(store, classBundle).In(vocab.Type).Iterate(ctx).AllValues(nil)
+	if err != nil {
+		return err
+	}
+	for _, b := range old {
+		quads, err := quadsOf(ctx, store, b)
+		if err != nil {
+			return err
+		}
+		for _, q := range quads {
+			tx.RemoveQuad(q)
+		}
+	}
+
+	stored, err := quadsOf(ctx, store, classBundle, predBundleItem, quad.IRI("support"), quad.IRI("confidence"), quad.IRI("lift"))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(stored))
+	for _, q := range stored {
+		existing[q.NQuad()] = true
+	}
+	for _, q := range meta {
+		if !existing[q.NQuad()] {
+			tx.AddQuad(q)
+		}
+	}
+
+	w := vocab.Labeled(txWriter{tx}, Label)
+	for _, b := range bundles {
+		if _, err := schema.WriteAsQuads(w, b); err != nil {
+			return err
+		}
+	}
+	return store.ApplyDeltas(tx.Deltas, graph.IgnoreOpts{})
+}
+
+// quadsOf returns the quads with these subjects, under whatever label they are stored
+func quadsOf(ctx context.Context, store *cayley.Handle, subjects ...quad.Value) ([]quad.Quad, error) {
+	var quads []quad.Quad
+	for _, s := range subjects {
+		ref := store.ValueOf(s)
+		if ref == nil {
+			continue
+		}
+		it := store.QuadIterator(quad.Subject, ref)
+		for it.Next(ctx) {
+			quads = append(quads, store.Quad(it.Result()))
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return quads, nil
+}
+
+// txWriter adds the quads written to it to a transaction
+type txWriter struct {
+	tx *graph.Transaction
+}
+
+func (w txWriter) WriteQuad(q quad.Quad) error {
+	w.tx.AddQuad(q)
+	return nil
+}
+
+func (w txWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	for _, q := range buf {
+		w.tx.AddQuad(q)
+	}
+	return len(buf), nil
+}
+
+// ForProduct returns the stored bundles that contain the product, highest lift first
+func ForProduct(ctx context.Context, store *cayley.Handle, product quad.Value) ([]Bundle, error) {
+	ids, err := cayley.StartPath(store, product).In(predBundleItem).Iterate(ctx).AllValues(nil)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var bundles []Bundle
+	if err := schema.LoadTo(ctx, store, &bundles, ids...); err != nil {
+		return nil, err
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		if bundles[i].Lift != bundles[j].Lift {
+			return bundles[i].Lift > bundles[j].Lift
+		}
+		return bundles[i].ID < bundles[j].ID
+	})
+	return bundles, nil
+}
@@ -0,0 +1,65 @@
+package bundles
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func TestReplace(t *testing.T) {
+	store := transactionStore(t)
+	before := countQuads(t, store)
+
+	ab := Bundle{ID: "bundle/a+b", Products: []quad.IRI{"a", "b"}, Support: 0.5, Confidence: 0.5, Lift: 1}
+	ac := Bundle{ID: "bundle/a+c", Products: []quad.IRI{"a", "c"}, Support: 0.5, Confidence: 1, Lift: 2}
+	bd := Bundle{ID: "bundle/b+d", Products: []quad.IRI{"b", "d"}, Support: 0.25, Confidence: 1, Lift: 1.5}
+
+	tests := []struct {
+		name    string
+		bundles []Bundle
+		want    map[quad.IRI][]Bundle
+	}{
+		{"first", []Bundle{ab, ac}, map[quad.IRI][]Bundle{"a": {ac, ab}, "b": {ab}, "c": {ac}, "d": nil}},
+		// ac is kept as it is, ab is removed
+		{"again", []Bundle{ac, bd}, map[quad.IRI][]Bundle{"a": {ac}, "b": {bd}, "c": {ac}, "d": {bd}}},
+		{"the same again", []Bundle{ac, bd}, map[quad.IRI][]Bundle{"a": {ac}, "b": {bd}, "c": {ac}, "d": {bd}}},
+		{"none", nil, map[quad.IRI][]Bundle{"a": nil, "b": nil, "c": nil, "d": nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Replace(context.TODO(), store, tt.bundles); err != nil {
+				t.Fatal(err)
+			}
+			for product, want := range tt.want {
+				got, err := ForProduct(context.TODO(), store, product)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("bundles of %s %+v, want %+v", product, got, want)
+				}
+			}
+		})
+	}
+
+	// only the metadata of the bundle class is left
+	if got, want := countQuads(t, store), before+len(meta); got != want {
+		t.Errorf("%d quads, want %d", got, want)
+	}
+}
+
+func countQuads(t *testing.T, store *cayley.Handle) int {
+	n := 0
+	it := store.QuadsAllIterator()
+	defer it.Close()
+	for it.Next(context.TODO()) {
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
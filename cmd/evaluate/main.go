@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/bolt"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/als"
+	"github.com/jtorvald/cayley-demo/evaluate"
+	"github.com/jtorvald/cayley-demo/recommend"
+)
+
+func main() {
+	// Some globally applicable things
+	graph.IgnoreMissing = true
+	graph.IgnoreDuplicates = true
+
+	file := flag.String("file", "", "Database file, as created by the recommendations command")
+	k := flag.Int("k", 5, "Number of recommendations to score per customer")
+	split := flag.String("split", "loo", "Holdout split: loo (leave one out) or time")
+	cutoff := flag.String("cutoff", "", "Purchases at or after this date (2006-01-02) are held out by the time split")
+	seed := flag.Int64("seed", 1, "Seed for the leave one out split")
+	strategies := flag.String("strategies", "count,jaccard,cosine,lift,random_walk,als", "Comma separated scoring strategies to evaluate, random_walk for the random walk recommender, als for an ALS model trained on the holdout")
+	walkSteps := flag.Int("walk-steps", 100000, "Number of steps of the random walk")
+	asJSON := flag.Bool("json", false, "Write the results as JSON instead of a table")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatalln("-file is required")
+	}
+
+	cfg := evaluate.Config{Split: evaluate.Split(*split), Seed: *seed}
+	if *cutoff != "" {
+		t, err := time.Parse("2006-01-02", *cutoff)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		cfg.Cutoff = t
+	}
+
+	store, err := cayley.NewGraph("bolt", *file, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	holdout, err := evaluate.NewHoldout(ctx, store, cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var reports []evaluate.Report
+	for _, name := range strings.Split(*strategies, ",") {
+		var rec evaluate.Recommender
+		switch name {
+		case string(recommend.StrategyRandomWalk):
+			// the training graph is loaded once for all the walks
+			graph, err := recommend.LoadWalkGraph(ctx, holdout.Train, recommend.Options{})
+			if err != nil {
+				log.Fatalln(err)
+			}
+			rec = randomWalk(recommend.WalkConfig{Steps: *walkSteps, Seed: *seed, Graph: graph}, *k)
+		case string(recommend.StrategyALS):
+			// the model only sees the training data, like the other recommenders
+			model, err := als.Train(ctx, holdout.Train, als.Config{Seed: *seed})
+			if err != nil {
+				log.Fatalln(err)
+			}
+			rec = modelForCustomer(model, *k)
+		default:
+			strategy, err := recommend.ParseStrategy(name)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			rec = forCustomer(strategy, *k)
+		}
+
+		report, err := holdout.Evaluate(ctx, "customer/"+name, rec, *k)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		err = evaluate.WriteJSON(os.Stdout, reports)
+	} else {
+		fmt.Printf("k = %d\n", *k)
+		err = evaluate.WriteTable(os.Stdout, reports)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// forCustomer evaluates recommend.ForCustomer with the given scoring strategy
+func forCustomer(strategy recommend.Strategy, k int) evaluate.Recommender {
+	return func(ctx context.Context, store *cayley.Handle, customer quad.Value) (recommend.ProductRecommendations, error) {
+		return recommend.ForCustomer(ctx, store, customer, recommend.Options{Strategy: strategy, Limit: k})
+	}
+}
+
+// modelForCustomer evaluates recommend.ModelForCustomer
+func modelForCustomer(model *als.Model, k int) evaluate.Recommender {
+	return func(ctx context.Context, store *cayley.Handle, customer quad.Value) (recommend.ProductRecommendations, error) {
+		return recommend.ModelForCustomer(ctx, store, model, customer, recommend.Options{Limit: k})
+	}
+}
+
+// randomWalk evaluates recommend.RandomWalkForCustomer
+func randomWalk(cfg recommend.WalkConfig, k int) evaluate.Recommender {
+	return func(ctx context.Context, store *cayley.Handle, customer quad.Value) (recommend.ProductRecommendations, error) {
+		return recommend.RandomWalkForCustomer(ctx, store, customer, cfg, recommend.Options{Limit: k})
+	}
+}
@@ -1,22 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"math/rand"
 	"time"
 
-	"sort"
-
 	"flag"
 
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
 	_ "github.com/cayleygraph/cayley/graph/bolt"
 	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/als"
+	"github.com/jtorvald/cayley-demo/bundles"
+	"github.com/jtorvald/cayley-demo/model"
+	"github.com/jtorvald/cayley-demo/reason"
+	"github.com/jtorvald/cayley-demo/recommend"
 	"github.com/satori/go.uuid"
 )
 
@@ -30,8 +37,81 @@ func main() {
 
 	file := flag.String("file", "", "File for the database")
 	randomCustomers := flag.Int("customers", 10, "Number of random customers to generate")
+	strategy := flag.String("strategy", "count", "Scoring strategy: count, jaccard, cosine or lift")
+	sameGroup := flag.Bool("same-group", false, "Only recommend products from the same group as the product")
+	textWeight := flag.Float64("text-weight", 0, "Blend the text similarity of the products into the product recommendations, from 0 to 1")
+	limit := flag.Int("limit", 0, "Maximum number of recommendations to show (0 for all)")
+	offset := flag.Int("offset", 0, "Number of recommendations to skip")
+	minCount := flag.Int("min-count", 0, "Minimum number of co-purchases for a recommendation")
+	minScore := flag.Float64("min-score", 0, "Minimum score for a recommendation")
+	exclude := flag.String("exclude", "purchased", "Which purchases to exclude from customer recommendations: purchased, recent_consumables or none")
+	blocklist := flag.String("blocklist", "", "Comma separated product ids to never recommend")
+	since := flag.String("since", "", "Only count co-purchases made at or after this date (2006-01-02 or RFC 3339)")
+	until := flag.String("until", "", "Only count co-purchases made before this date (2006-01-02 or RFC 3339)")
+	halfLife := flag.Duration("half-life", 0, "Let co-purchases count half as much every half-life, e.g. 720h (0 disables the decay)")
+	showUnavailable := flag.Bool("show-unavailable", false, "Show unavailable products marked as back in stock soon instead of leaving them out")
+	rulesFile := flag.String("rules", "", "JSON file with re-ranking rules: pin, boost, bury, price band, stock and diversity")
+	noFallback := flag.Bool("no-fallback", false, "Do not fill up short results with bestsellers and new products")
+	basket := flag.String("basket", "", "Comma separated product ids, with an optional :weight, to recommend a basket for")
+	mineBundles := flag.Bool("mine-bundles", false, "Mine frequently bought together bundles and store them in the graph")
+	minSupport := flag.Float64("min-support", 0.05, "Minimal fraction of customers that bought a whole bundle")
+	minConfidence := flag.Float64("min-confidence", 0.2, "Minimal confidence of a bundle rule")
+	minLift := flag.Float64("min-lift", 1, "Minimal lift of a bundle rule")
+	walkSteps := flag.Int("walk-steps", 0, "Also recommend with a random walk of this many steps (0 to skip)")
+	restart := flag.Float64("restart", 0.15, "Restart probability of the random walk")
+	useModel := flag.Bool("als", false, "Also recommend with the ALS model saved by the train subcommand")
+	factors := flag.Int("factors", 10, "Number of latent factors of the ALS model (train)")
+	iterations := flag.Int("iterations", 10, "Number of ALS iterations (train)")
+	lambda := flag.Float64("lambda", 0.1, "Regularization of the ALS model (train)")
+	alpha := flag.Float64("alpha", 40, "Confidence scale of a purchase for the ALS model (train)")
+	instancesOf := flag.String("instances-of", "", "List the entities of this class, also through its subclasses, and exit")
+	validate := flag.Bool("validate", false, "Validate the data against the registered classes and exit")
+	httpAddr := flag.String("http", "", "Address to serve the JSON API on, e.g. :8080 (disabled when empty)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [train]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	train := flag.Arg(0) == "train"
+	if flag.NArg() > 1 || (flag.NArg() == 1 && !train) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if train && *file == "" {
+		log.Fatalln("train needs -file, the model is saved next to the database")
+	}
+	if *limit < 0 || *offset < 0 {
+		log.Fatalln("-limit and -offset cannot be negative")
+	}
+	if !(*restart > 0 && *restart < 1) {
+		log.Fatalln("-restart must be between 0 and 1")
+	}
+
+	scoring, err := recommend.ParseStrategy(*strategy)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	exclusion, err := recommend.ParseExclusion(*exclude)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var rules *recommend.Rules
+	if *rulesFile != "" {
+		rules, err = recommend.LoadRules(*rulesFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	fileExisted := false
 	if *file != "" {
 		t = *file
@@ -53,226 +133,251 @@ func main() {
 		addQuads(store, *randomCustomers) // add quads to the graph
 	}
 
-	// John Doe
-	id := quad.IRI("3117979d-516a-4bac-a55e-b71g4dcb2351")
-
-	// list products that John bought
-	findProductsForCustomer(store, id)
+	if *validate {
+		violations, err := model.Validate(context.Background(), store)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+		fmt.Printf("%d violations\n", len(violations))
+		return
+	}
 
-	// find product recommendations for John
-	findProductRecommendationsForCustomer(store, id)
+	if train {
+		trained, err := als.Train(context.Background(), store, als.Config{
+			Factors:    *factors,
+			Iterations: *iterations,
+			Lambda:     *lambda,
+			Alpha:      *alpha,
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := trained.Save(als.ModelFile(t)); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Trained ALS model for %d customers and %d products: %s\n", len(trained.Users), len(trained.Items), als.ModelFile(t))
+		return
+	}
 
-	// find product recommendations for trackball
-	findProductRecommendationsForProduct(store, quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2364"))
+	if *instancesOf != "" {
+		instances, err := reason.RDFS.InstancesOf(context.Background(), store, quad.IRI(*instancesOf))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, v := range instances {
+			fmt.Println(v)
+		}
+		fmt.Printf("%d instances of %s\n", len(instances), *instancesOf)
+		return
+	}
 
-}
+	if *mineBundles {
+		found, err := bundles.Mine(context.Background(), store, bundles.Config{
+			MinSupport:    *minSupport,
+			MinConfidence: *minConfidence,
+			MinLift:       *minLift,
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := bundles.Replace(context.Background(), store, found); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Stored %d bundles\n", len(found))
+	}
 
-type ProductRecommendation struct {
-	ProductID string
-	Name      string
-	Count     int32
-}
+	opts := recommend.Options{
+		Strategy:        scoring,
+		SameGroup:       *sameGroup,
+		TextWeight:      *textWeight,
+		Limit:           *limit,
+		Offset:          *offset,
+		MinCount:        int32(*minCount),
+		MinScore:        *minScore,
+		Exclusion:       exclusion,
+		Blocklist:       splitList(*blocklist),
+		Since:           sinceTime,
+		Until:           untilTime,
+		HalfLife:        *halfLife,
+		Rules:           rules,
+		ShowUnavailable: *showUnavailable,
+		NoFallback:      *noFallback,
+	}
 
-type ProductRecommendations []ProductRecommendation
+	ctx := context.Background()
 
-func (slice ProductRecommendations) Len() int {
-	return len(slice)
-}
+	// the products do not change from here on, so they are indexed once
+	index, err := recommend.NewTextIndex(ctx, store)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	opts.TextIndex = index
 
-func (slice ProductRecommendations) Less(i, j int) bool {
-	return slice[i].Count > slice[j].Count
-}
+	if *httpAddr != "" {
+		fmt.Printf("Serving API on %s\n", *httpAddr)
+		// the flags are the defaults of the query parameters
+		log.Fatal(http.ListenAndServe(*httpAddr, newServer(store, opts)))
+	}
 
-func (slice ProductRecommendations) Swap(i, j int) {
-	slice[i], slice[j] = slice[j], slice[i]
-}
+	// John Doe
+	id := quad.IRI("3117979d-516a-4bac-a55e-b71g4dcb2351")
 
-func findProductsForCustomer(store *cayley.Handle, to quad.Value) {
-	fmt.Printf("\nFind products bought by customer (%s):\n", to)
+	// list products that John bought
+	fmt.Printf("\nFind products bought by customer (%s):\n", id)
 	fmt.Printf("============================================\n")
+	products, err := recommend.ProductsForCustomer(ctx, store, id)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, product := range products {
+		fmt.Printf("%s %s %s\n", id, product.ProductID, product.Name)
+	}
 
-	// start from the custoemr
-	current_customer := cayley.StartPath(store, to)
-
-	p := current_customer.Out(quad.IRI("bought")).Tag("product").Save(quad.IRI("label"), "name")
-	// display all the product recommendations
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		fmt.Printf("%s %s %s\n", to.String(), m["product"], m["name"])
-
-	})
-}
-
-// c1 -> products1 -> group <- products2 (- products1) <- c2
-func findProductRecommendationsForCustomer(store *cayley.Handle, to quad.Value) {
-	fmt.Printf("\nFind product recommendations for customer (%s):\n", to)
+	// find product recommendations for John
+	fmt.Printf("\nFind product recommendations for customer (%s):\n", id)
 	fmt.Printf("============================================\n")
+	recommendations, err := recommend.ForCustomer(ctx, store, id, opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	printRecommendations(recommendations)
 
-	// start from the custoemr
-	current_customer := cayley.StartPath(store, to)
-
-	// find everybody that knows TO
-	pred_bought := quad.IRI("bought")
-	pred_product_group := quad.IRI("in_group")
-	pred_label := quad.IRI("label")
-	pred_firstname := quad.IRI("firstname")
-
-	// find the articles the customer bought (to exclude later)
-	customer_articles := current_customer.Out(pred_bought) //.Unique() // this one makes it a bit slower
-	product_groups := customer_articles.Out(pred_product_group).Unique()
-
-	// who else bought these articles?
-	p := product_groups.In(pred_product_group).Except(customer_articles).Tag("product").Save(pred_label, "name")
-
-	p = p.InWithTags([]string{"predicate"}, pred_bought).Tag("customer").Save(pred_firstname, "client_name") // c2
+	// find product recommendations for trackball
+	trackball := quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2364")
+	fmt.Printf("\nFind product recommendations for product (%s):\n", trackball)
+	fmt.Printf("============================================\n")
+	recommendations, err = recommend.ForProduct(ctx, store, trackball, opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	printRecommendations(recommendations)
 
-	recmap := make(map[string]ProductRecommendation)
+	// find products described like the trackball
+	fmt.Printf("\nFind similar products for product (%s):\n", trackball)
+	fmt.Printf("============================================\n")
+	recommendations, err = recommend.SimilarProducts(ctx, store, index, trackball, opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	printRecommendations(recommendations)
+
+	if *walkSteps > 0 {
+		fmt.Printf("\nFind product recommendations for customer with a random walk (%s):\n", id)
+		fmt.Printf("============================================\n")
+		recommendations, err = recommend.RandomWalkForCustomer(ctx, store, id, recommend.WalkConfig{Restart: *restart, Steps: *walkSteps}, opts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		printRecommendations(recommendations)
+	}
 
-	// display all the product recommendations
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		//fmt.Printf("%s %s `%s`-> %s %s\n", m["customer"], m["client_name"], m["predicate"], m["product"], m["name"])
-		if _, ok := recmap[m["product"].String()]; !ok {
-			r := ProductRecommendation{}
-			r.Name = m["name"].String()
-			r.ProductID = m["product"].String()
-			recmap[m["product"].String()] = r
+	if *useModel {
+		trained, err := als.Load(als.ModelFile(t))
+		if err != nil {
+			log.Fatalln(err)
 		}
-		obj := recmap[m["product"].String()]
-		obj.Count++
-		recmap[m["product"].String()] = obj
-	})
-	recommendations := ProductRecommendations{}
-	for _, r := range recmap {
-		recommendations = append(recommendations, r)
+		fmt.Printf("\nFind product recommendations for customer with the ALS model (%s):\n", id)
+		fmt.Printf("============================================\n")
+		recommendations, err = recommend.ModelForCustomer(ctx, store, trained, id, opts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		printRecommendations(recommendations)
 	}
-	sort.Sort(recommendations)
-	fmt.Printf("%v\n", recommendations)
-}
 
-// product1 -> group <- products2 (- product1) <- c2 (+ product_id)
-func findProductRecommendationsForProduct(store *cayley.Handle, product_id quad.Value) {
-	fmt.Printf("\nFind product recommendations for product (%s):\n", product_id)
+	// bundles with the trackball
+	fmt.Printf("\nFind bundles for product (%s):\n", trackball)
 	fmt.Printf("============================================\n")
+	found, err := bundles.ForProduct(ctx, store, trackball)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, b := range found {
+		fmt.Printf("%v support %.3f confidence %.3f lift %.3f\n", b.Products, b.Support, b.Confidence, b.Lift)
+	}
 
-	// start from the custoemr
-	current_product := cayley.StartPath(store, product_id)
-
-	// find everybody that knows TO
-	pred_bought := quad.IRI("bought")
-	pred_product_group := quad.IRI("in_group")
-	pred_label := quad.IRI("label")
-	pred_firstname := quad.IRI("firstname")
-
-	// find the articles the customer bought (to exclude later)
-	customer_articles := current_product
-	product_groups := customer_articles.Out(pred_product_group).Unique()
-
-	// what did they buy?
-	p := product_groups.In(pred_product_group).Except(customer_articles).Tag("product").Save(pred_label, "name")
-
-	// who bought it that also bought the same product
-	//.Filter(iterator.CompareGT, quad.Value(time.Date(2017, 01, 23, 0, 0, 0, 0, nil))
-	// Tag().Out().Filter().Back()
-	p = p.InWithTags([]string{"predicate"}, pred_bought).Has(pred_bought, product_id).Tag("customer").Save(pred_firstname, "client_name") // c2
-
-	recmap := make(map[string]ProductRecommendation)
-
-	// display all the product recommendations
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		//fmt.Printf("%s %s `%s`-> %s %s\n", m["customer"], m["client_name"], m["predicate"], m["product"], m["name"])
-
-		if _, ok := recmap[m["product"].String()]; !ok {
-			r := ProductRecommendation{}
-			r.Name = m["name"].String()
-			r.ProductID = m["product"].String()
-			recmap[m["product"].String()] = r
+	if *basket != "" {
+		items, err := parseBasket(*basket)
+		if err != nil {
+			log.Fatalln(err)
 		}
-		obj := recmap[m["product"].String()]
-		obj.Count++
-		recmap[m["product"].String()] = obj
-	})
-
-	recommendations := ProductRecommendations{}
-	for _, r := range recmap {
-		recommendations = append(recommendations, r)
+		fmt.Printf("\nFind product recommendations for basket (%s):\n", *basket)
+		fmt.Printf("============================================\n")
+		recommendations, err = recommend.ForBasket(ctx, store, items, opts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		printRecommendations(recommendations)
 	}
-	sort.Sort(recommendations)
-	fmt.Printf("%v\n", recommendations)
-}
 
-func lookAtFriendsOfFriends(store *cayley.Handle, to quad.Value) {
-	fmt.Printf("\nlookAtFriendsOfFriends for subject (%s):\n", to)
+	// load John and the trackball back into structs
+	fmt.Printf("\nLoad customer and product with the schema (%s, %s):\n", id, trackball)
 	fmt.Printf("============================================\n")
+	client, err := model.LoadClient(ctx, store, id)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	product, err := model.LoadProduct(ctx, store, trackball)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("%+v\n%+v\n", client, product)
 
-	p := cayley.StartPath(store, to)
-
-	// find everybody that knows TO
-	p = p.Tag("subject").OutWithTags([]string{"predicate"}, quad.Raw("knows")).Tag("friend")
-
-	// display everybody that TO knows
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["friend"])
-	})
-
-	// and from there all 'friends of friends'
-	p = p.Tag("friend").OutWithTags([]string{"predicate"}, quad.Raw("knows")).Tag("friend_of_friend")
-
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		fmt.Printf("%s `%s`-> %s\n", m["friend"], m["predicate"], m["friend_of_friend"])
-	})
 }
 
-// countOuts ... well, counts Outs
-func countOuts(store *cayley.Handle, to quad.Value) {
-	p := cayley.StartPath(store, to).Out().Count()
-	fmt.Printf("\n\ncountOuts for %s: ", to)
-	p.Iterate(nil).EachValue(store, func(v quad.Value) {
-		fmt.Printf("%d\n", quad.NativeOf(v))
-	})
-	fmt.Printf("============================================\n")
+func printRecommendations(recommendations recommend.ProductRecommendations) {
+	for _, r := range recommendations {
+		fmt.Printf("%s %s %.3f (%s)", r.ProductID, r.Name, r.Score, r.Tier)
+		if r.BackInStockSoon {
+			fmt.Print(" [back in stock soon]")
+		}
+		if r.Explanation != nil {
+			fmt.Printf(": %s", r.Explanation.Text)
+		}
+		fmt.Println()
+	}
 }
 
-// countIns... well, counts Ins
-func countIns(store *cayley.Handle, to quad.Value) {
-	p := cayley.StartPath(store, to).In().Count()
-	fmt.Printf("\n\ncountIns for %s: ", to)
-	p.Iterate(nil).EachValue(store, func(v quad.Value) {
-		fmt.Printf("%d\n", quad.NativeOf(v))
-	})
-	fmt.Printf("============================================\n")
+// splitList splits a comma separated flag value, an empty value gives no items
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
 }
 
-// lookAtOuts looks at the outbound links from the "to" node
-func lookAtOuts(store *cayley.Handle, to quad.Value) {
-	p := cayley.StartPath(store, to) // start from a single node, but we could start from multiple
-
-	// this gives us a path with all the output predicates from our starting point
-	p = p.Tag("subject").OutWithTags([]string{"predicate"}).Tag("object")
-
-	fmt.Printf("\nlookAtOuts: subject (%s) -predicate-> object\n", to)
-	fmt.Printf("============================================\n")
-
-	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["object"])
-		if m["predicate"] == quad.Raw("follows") {
-
-			p = cayley.StartPath(store, m["object"]).Tag("subject").OutWithTags([]string{"predicate"}).Tag("object")
-
-			p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-				fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["object"])
-			})
+// parseBasket parses a basket as id[:weight],id[:weight]
+func parseBasket(s string) ([]recommend.BasketItem, error) {
+	var items []recommend.BasketItem
+	for _, item := range splitList(s) {
+		b := recommend.BasketItem{ProductID: item}
+		if i := strings.LastIndex(item, ":"); i >= 0 {
+			weight, err := strconv.ParseFloat(item[i+1:], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight in %q: %v", item, err)
+			}
+			if !(weight > 0) {
+				return nil, fmt.Errorf("weight in %q must be above 0", item)
+			}
+			b.ProductID, b.Weight = item[:i], weight
 		}
-	})
+		items = append(items, b)
+	}
+	return items, nil
 }
 
-// lookAtIns looks at the inbound links to the "to" node
-func lookAtIns(store *cayley.Handle, to quad.Value) {
-	fmt.Printf("\nlookAtIns: object <-predicate- subject (%s)\n", to)
-	fmt.Printf("=============================================\n")
-
-	cayley.StartPath(store, to).Tag("object").InWithTags([]string{"predicate"}).Tag("subject").Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-		fmt.Printf("%s <-`%s` %s\n", m["object"], m["predicate"], m["subject"])
-	})
-
+// parseTime parses a date (2006-01-02) or an RFC 3339 time, an empty value gives the zero time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
 
 func initializeAndOpenGraph(atLoc string) *cayley.Handle {
@@ -308,6 +413,23 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasProperty"), quad.IRI("label"), "catalog"))
 	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasProperty"), quad.IRI("desc"), "catalog"))
 	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasProperty"), quad.IRI("price"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("consumable"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("in_group"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("added"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("stock"), "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("available"), "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("hasOptionalProperty"), quad.IRI("discontinued"), "inventory"))
+
+	// the values the catalog properties hold
+	tr.WriteQuad(quad.Make(quad.IRI("label"), quad.IRI("range"), quad.IRI("string"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("desc"), quad.IRI("range"), quad.IRI("string"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("price"), quad.IRI("range"), quad.IRI("float"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("consumable"), quad.IRI("range"), quad.IRI("bool"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("in_group"), quad.IRI("range"), quad.IRI("iri"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("added"), quad.IRI("range"), quad.IRI("time"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("stock"), quad.IRI("range"), quad.IRI("int"), "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("available"), quad.IRI("range"), quad.IRI("bool"), "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("discontinued"), quad.IRI("range"), quad.IRI("bool"), "inventory"))
 
 	// register type product_group
 	tr.WriteQuad(quad.Make(quad.IRI("product_group"), quad.IRI("type"), quad.IRI("class"), "catalog"))
@@ -316,25 +438,29 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 	tr.WriteQuad(quad.Make(quad.IRI("product_group"), quad.IRI("hasProperty"), quad.IRI("label"), "catalog"))
 	tr.WriteQuad(quad.Make(quad.IRI("product_group"), quad.IRI("hasProperty"), quad.IRI("desc"), "catalog"))
 
+	// products and product groups are both entries of the catalog, try -instances-of catalog_entry
+	tr.WriteQuad(quad.Make(quad.IRI("product"), quad.IRI("subClassOf"), quad.IRI("catalog_entry"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("product_group"), quad.IRI("subClassOf"), quad.IRI("catalog_entry"), "catalog"))
+
 	// add product group: electronics
 	tr.WriteQuad(quad.Make(quad.IRI("electronics"), quad.IRI("type"), quad.IRI("product_group"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("electronics"), quad.IRI("label"), quad.IRI("Electronics"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("electronics"), quad.IRI("desc"), quad.IRI("Electronics for in and around the house"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("electronics"), quad.IRI("label"), "Electronics", "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("electronics"), quad.IRI("desc"), "Electronics for in and around the house", "catalog"))
 
 	// add product group: bed
 	tr.WriteQuad(quad.Make(quad.IRI("bedroom"), quad.IRI("type"), quad.IRI("product_group"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("bedroom"), quad.IRI("label"), quad.IRI("Bedroom"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("bedroom"), quad.IRI("desc"), quad.IRI("Everything for in the bedroom"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("bedroom"), quad.IRI("label"), "Bedroom", "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("bedroom"), quad.IRI("desc"), "Everything for in the bedroom", "catalog"))
 
 	// household
 	tr.WriteQuad(quad.Make(quad.IRI("utensils"), quad.IRI("type"), quad.IRI("product_group"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("utensils"), quad.IRI("label"), quad.IRI("Utensils"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("utensils"), quad.IRI("desc"), quad.IRI("Every tool you need in house"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("utensils"), quad.IRI("label"), "Utensils", "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("utensils"), quad.IRI("desc"), "Every tool you need in house", "catalog"))
 
 	// household
 	tr.WriteQuad(quad.Make(quad.IRI("household"), quad.IRI("type"), quad.IRI("product_group"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("household"), quad.IRI("label"), quad.IRI("Household"), "catalog"))
-	tr.WriteQuad(quad.Make(quad.IRI("household"), quad.IRI("desc"), quad.IRI("Everything for your household"), "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("household"), quad.IRI("label"), "Household", "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("household"), quad.IRI("desc"), "Everything for your household", "catalog"))
 
 	// register type client
 	tr.WriteQuad(quad.Make(quad.IRI("client"), quad.IRI("type"), quad.IRI("class"), "crm"))
@@ -342,30 +468,56 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 	tr.WriteQuad(quad.Make(quad.IRI("client"), quad.IRI("desc"), "A person who placed an order", "crm"))
 	tr.WriteQuad(quad.Make(quad.IRI("client"), quad.IRI("hasProperty"), quad.IRI("firstname"), "crm"))
 	tr.WriteQuad(quad.Make(quad.IRI("client"), quad.IRI("hasProperty"), quad.IRI("lastname"), "crm"))
+	tr.WriteQuad(quad.Make(quad.IRI("client"), quad.IRI("hasOptionalProperty"), quad.IRI("has_purchase"), "crm"))
+	tr.WriteQuad(quad.Make(quad.IRI("firstname"), quad.IRI("range"), quad.IRI("string"), "crm"))
+	tr.WriteQuad(quad.Make(quad.IRI("lastname"), quad.IRI("range"), quad.IRI("string"), "crm"))
+	tr.WriteQuad(quad.Make(quad.IRI("has_purchase"), quad.IRI("range"), quad.IRI("iri"), "crm"))
+
+	// register type purchase, a line item of an order
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("type"), quad.IRI("class"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("label"), "Purchase", "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("desc"), "A quantity of a product bought by a client", "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("hasProperty"), quad.IRI("purchase_of"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("hasProperty"), quad.IRI("at"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("hasProperty"), quad.IRI("quantity"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase"), quad.IRI("hasProperty"), quad.IRI("unit_price"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("purchase_of"), quad.IRI("range"), quad.IRI("iri"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("at"), quad.IRI("range"), quad.IRI("time"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("quantity"), quad.IRI("range"), quad.IRI("int"), "sales"))
+	tr.WriteQuad(quad.Make(quad.IRI("unit_price"), quad.IRI("range"), quad.IRI("float"), "sales"))
 
 	// add clients
-	tr.WriteQuads(generateClientQuads("3117979d-516a-4bac-a55e-b71g4dcb2351", "John", "Doe"))
-	tr.WriteQuads(generateClientQuads("3217979d-516a-4bac-a55e-b71f4dcb2352", "Alice", "Blue"))
-	tr.WriteQuads(generateClientQuads("3317979d-516a-4bac-a55e-b71e4dcb2353", "Jase", "Folli"))
-	tr.WriteQuads(generateClientQuads("3417979d-516a-4bac-a55e-b71d4dcb2355", "Casper", "Walden"))
+	addClient(tr, "3117979d-516a-4bac-a55e-b71g4dcb2351", "John", "Doe")
+	addClient(tr, "3217979d-516a-4bac-a55e-b71f4dcb2352", "Alice", "Blue")
+	addClient(tr, "3317979d-516a-4bac-a55e-b71e4dcb2353", "Jase", "Folli")
+	addClient(tr, "3417979d-516a-4bac-a55e-b71d4dcb2355", "Casper", "Walden")
 
 	// products
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2351", "Walkman", "This is a description", 12.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2352", "Discman", "This is a description", 34.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2353", "Walky talky", "This is a description", 12.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2354", "Pencil", "This is a description", 76.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2355", "Pen", "This is a description", 2.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2356", "Pillow", "This is a description", 54.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2357", "Blanket", "This is a description", 34.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2358", "Sheets", "This is a description", 52.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2359", "Bucket", "This is a description", 21.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2360", "Monitor", "This is a description", 321.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2361", "Laptop", "This is a description", 426.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2362", "Keyboard", "This is a description", 34.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2363", "Mouse", "This is a description", 12.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2364", "Trackball", "This is a description", 23.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2365", "Harddrive", "This is a description", 202.3))
-	tr.WriteQuads(generateProductQuads("2017979d-516a-4bac-a55e-b71c4dcb2366", "MagSafe Adapter", "This is a description", 86.3))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2351", "Walkman", "This is a description", 12.3, date(2016, 12, 1))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2352", "Discman", "This is a description", 34.3, date(2016, 12, 2))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2353", "Walky talky", "This is a description", 12.3, date(2016, 12, 3))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2354", "Pencil", "This is a description", 76.3, date(2016, 12, 4))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2355", "Pen", "This is a description", 2.3, date(2016, 12, 5))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2356", "Pillow", "This is a description", 54.3, date(2016, 12, 6))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2357", "Blanket", "This is a description", 34.3, date(2016, 12, 7))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2358", "Sheets", "This is a description", 52.3, date(2016, 12, 8))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2359", "Bucket", "This is a description", 21.3, date(2016, 12, 9))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2360", "Monitor", "This is a description", 321.3, date(2016, 12, 10))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2361", "Laptop", "This is a description", 426.3, date(2016, 12, 11))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2362", "Keyboard", "This is a description", 34.3, date(2016, 12, 12))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2363", "Mouse", "This is a description", 12.3, date(2016, 12, 13))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2364", "Trackball", "This is a description", 23.3, date(2016, 12, 14))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2365", "Harddrive", "This is a description", 202.3, date(2016, 12, 15))
+	addProduct(tr, "2017979d-516a-4bac-a55e-b71c4dcb2366", "MagSafe Adapter", "This is a description", 86.3, date(2016, 12, 16))
+
+	// consumables run out and are bought again
+	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2354"), quad.IRI("consumable"), true, "catalog"))
+	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2355"), quad.IRI("consumable"), true, "catalog"))
+
+	// the discman is discontinued and the walky talky is sold out
+	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2352"), quad.IRI("discontinued"), true, "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2353"), quad.IRI("available"), true, "inventory"))
+	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2353"), quad.IRI("stock"), 0, "inventory"))
 
 	// put products in their groups
 	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2351"), quad.IRI("in_group"), quad.IRI("electronics"), "catalog"))
@@ -388,19 +540,19 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 
 	// add purchases
 	// john bought a walkman
-	tr.WriteQuad(quad.Make(quad.IRI("3117979d-516a-4bac-a55e-b71g4dcb2351"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2351"), "sales"))
+	addPurchase(tr, "3117979d-516a-4bac-a55e-b71g4dcb2351", "2017979d-516a-4bac-a55e-b71c4dcb2351", date(2017, 1, 10), 1)
 	// and a monitor
-	tr.WriteQuad(quad.Make(quad.IRI("3117979d-516a-4bac-a55e-b71g4dcb2351"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2360"), "sales"))
+	addPurchase(tr, "3117979d-516a-4bac-a55e-b71g4dcb2351", "2017979d-516a-4bac-a55e-b71c4dcb2360", date(2017, 1, 10), 1)
 
 	// alice bought a pencil and a walkman
-	tr.WriteQuad(quad.Make(quad.IRI("3217979d-516a-4bac-a55e-b71f4dcb2352"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2354"), "sales"))
-	tr.WriteQuad(quad.Make(quad.IRI("3217979d-516a-4bac-a55e-b71f4dcb2352"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2351"), "sales"))
-	tr.WriteQuad(quad.Make(quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2364"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2351"), "sales"))
+	addPurchase(tr, "3217979d-516a-4bac-a55e-b71f4dcb2352", "2017979d-516a-4bac-a55e-b71c4dcb2354", date(2017, 2, 3), 1)
+	addPurchase(tr, "3217979d-516a-4bac-a55e-b71f4dcb2352", "2017979d-516a-4bac-a55e-b71c4dcb2351", date(2017, 2, 3), 1)
+	addPurchase(tr, "2017979d-516a-4bac-a55e-b71c4dcb2364", "2017979d-516a-4bac-a55e-b71c4dcb2351", date(2017, 2, 5), 1)
 
 	// casper bought a harddrive pencil and a walkman
-	tr.WriteQuad(quad.Make(quad.IRI("3417979d-516a-4bac-a55e-b71d4dcb2355"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2365"), "sales"))
-	tr.WriteQuad(quad.Make(quad.IRI("3417979d-516a-4bac-a55e-b71d4dcb2355"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2354"), "sales"))
-	tr.WriteQuad(quad.Make(quad.IRI("3417979d-516a-4bac-a55e-b71d4dcb2355"), quad.IRI("bought"), quad.IRI("2017979d-516a-4bac-a55e-b71c4dcb2351"), "sales"))
+	addPurchase(tr, "3417979d-516a-4bac-a55e-b71d4dcb2355", "2017979d-516a-4bac-a55e-b71c4dcb2365", date(2017, 3, 14), 1)
+	addPurchase(tr, "3417979d-516a-4bac-a55e-b71d4dcb2355", "2017979d-516a-4bac-a55e-b71c4dcb2354", date(2017, 3, 14), 1)
+	addPurchase(tr, "3417979d-516a-4bac-a55e-b71d4dcb2355", "2017979d-516a-4bac-a55e-b71c4dcb2351", date(2017, 3, 14), 1)
 
 	randomproducts := []string{
 		"2017979d-516a-4bac-a55e-b71c4dcb2351",
@@ -423,17 +575,17 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 	// now create some random data
 	for i := 0; i < randomCustomers; i++ {
 		userId := uuid.NewV4().String()
-		tr.WriteQuads(generateClientQuads(userId, fmt.Sprintf("User %d", i), fmt.Sprintf("Lastname %d", i)))
+		addClient(tr, userId, fmt.Sprintf("User %d", i), fmt.Sprintf("Lastname %d", i))
 
 		rand.Seed(time.Now().UnixNano())
 		n := rand.Int() % len(randomproducts)
 
-		// bought two random products
-		tr.WriteQuad(quad.Make(quad.IRI(userId), quad.IRI("bought"), quad.IRI(randomproducts[n]), "sales"))
+		// bought two random products, some time in the last three years
+		addPurchase(tr, userId, randomproducts[n], randomTime(), 1+rand.Intn(3))
 
 		rand.Seed(time.Now().UnixNano())
 		n = rand.Int() % len(randomproducts)
-		tr.WriteQuad(quad.Make(quad.IRI(userId), quad.IRI("bought"), quad.IRI(randomproducts[n]), "sales"))
+		addPurchase(tr, userId, randomproducts[n], randomTime(), 1+rand.Intn(3))
 	}
 
 	err := tr.Close()
@@ -443,20 +595,42 @@ func addQuads(store *cayley.Handle, randomCustomers int) {
 
 }
 
-func generateClientQuads(id, firstname, lastname string) []quad.Quad {
-	quads := make([]quad.Quad, 0, 3)
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("firstname"), firstname, "crm"))
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("lastname"), lastname, "crm"))
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("type"), quad.IRI("client"), "crm"))
-	return quads
+func addClient(w quad.Writer, id, firstname, lastname string) {
+	err := model.SaveClient(w, model.Client{ID: quad.IRI(id), Firstname: firstname, Lastname: lastname})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// prices of the added products, purchases are made at these prices
+var prices = make(map[string]float64)
+
+func addProduct(w quad.Writer, id, title, description string, price float64, added time.Time) {
+	err := model.SaveProduct(w, model.Product{ID: quad.IRI(id), Label: title, Desc: description, Price: price, Added: added})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	prices[id] = price
+}
+
+func addPurchase(w quad.Writer, client, product string, at time.Time, quantity int) {
+	err := model.SavePurchase(w, model.Purchase{
+		Client:    quad.IRI(client),
+		Product:   quad.IRI(product),
+		At:        at,
+		Quantity:  quantity,
+		UnitPrice: prices[product],
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
 }
-func generateProductQuads(id, title, description string, price float32) []quad.Quad {
-	quads := make([]quad.Quad, 0, 4)
 
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("label"), title, "catalog"))
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("desc"), description, "catalog"))
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("price"), price, "catalog"))
-	quads = append(quads, quad.Make(quad.IRI(id), quad.IRI("type"), quad.IRI("product"), "catalog"))
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
 
-	return quads
+// randomTime returns a time in the last three years
+func randomTime() time.Time {
+	return time.Now().Add(-time.Duration(rand.Int63n(int64(3 * 365 * 24 * time.Hour))))
 }
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/bundles"
+	"github.com/jtorvald/cayley-demo/model"
+	"github.com/jtorvald/cayley-demo/recommend"
+)
+
+// newServer returns a handler that exposes the recommendation queries as a JSON API:
+//
+//	GET /customers/{id}/products
+//	GET /customers/{id}/recommendations
+//	GET /products/{id}/recommendations
+//	GET /products/{id}/similar
+//	GET /products/{id}/bundles
+//	GET /products/{id}/inventory
+//	PUT /products/{id}/inventory
+//	GET /basket/recommendations?products=id[:weight],id[:weight]
+//
+// The recommendation endpoints accept ?strategy=count|jaccard|cosine|lift, the product
+// recommendations also ?same_group=true and ?text_weight=0.3 to blend in the text similarity
+// of /similar. Results are selected with ?limit, ?offset, ?min_count and ?min_score. Customer recommendations take ?exclude=purchased|recent_consumables|none,
+// all of them ?blocklist=id,id. Co-purchases are limited to a time window with ?since and ?until
+// and decayed with ?half_life=720h. ?no_fallback=true disables the bestseller fallback and
+// ?show_unavailable=true shows unavailable products marked back_in_stock_soon.
+// The query parameters override the defaults, which also hold the re-ranking rules and the
+// text index of /similar and ?text_weight. The server only writes inventory, which is not
+// indexed, so the index is never refreshed.
+func newServer(store *cayley.Handle, defaults recommend.Options) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/customers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, action, ok := splitResourcePath(r, "/customers/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		opts, err := optionsFromQuery(r, defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch action {
+		case "products":
+			products, err := recommend.ProductsForCustomer(r.Context(), store, quad.IRI(id))
+			writeResult(w, products, err)
+		case "recommendations":
+			recommendations, err := recommend.ForCustomer(r.Context(), store, quad.IRI(id), opts)
+			writeResult(w, recommendations, err)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/products/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, ok := splitResourcePath(r, "/products/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if action == "inventory" {
+			inventory(w, r, store, quad.IRI(id))
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		opts, err := optionsFromQuery(r, defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch action {
+		case "recommendations":
+			recommendations, err := recommend.ForProduct(r.Context(), store, quad.IRI(id), opts)
+			writeResult(w, recommendations, err)
+		case "similar":
+			recommendations, err := recommend.SimilarProducts(r.Context(), store, defaults.TextIndex, quad.IRI(id), opts)
+			writeResult(w, recommendations, err)
+		case "bundles":
+			found, err := bundles.ForProduct(r.Context(), store, quad.IRI(id))
+			writeResult(w, found, err)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/basket/recommendations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		opts, err := optionsFromQuery(r, defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		basket, err := parseBasket(r.URL.Query().Get("products"))
+		if err != nil || len(basket) == 0 {
+			http.Error(w, "products must list the basket as id[:weight],id[:weight]", http.StatusBadRequest)
+			return
+		}
+
+		recommendations, err := recommend.ForBasket(r.Context(), store, basket, opts)
+		writeResult(w, recommendations, err)
+	})
+
+	return mux
+}
+
+// splitResourcePath splits /prefix/{id}/{action} into id and action
+func splitResourcePath(r *http.Request, prefix string) (id, action string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// optionsFromQuery reads the recommendation options from the query string, e.g. ?strategy=jaccard,
+// on top of the defaults
+func optionsFromQuery(r *http.Request, defaults recommend.Options) (recommend.Options, error) {
+	opts := defaults
+	var err error
+
+	q := r.URL.Query()
+	if v := q.Get("strategy"); v != "" {
+		opts.Strategy, err = recommend.ParseStrategy(v)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if v := q.Get("same_group"); v != "" {
+		opts.SameGroup, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid same_group: %v", err)
+		}
+	}
+
+	if v := q.Get("text_weight"); v != "" {
+		opts.TextWeight, err = strconv.ParseFloat(v, 64)
+		if err != nil || opts.TextWeight < 0 || opts.TextWeight > 1 {
+			return opts, fmt.Errorf("invalid text_weight: %q", v)
+		}
+	}
+
+	for name, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if v := q.Get(name); v != "" {
+			*dst, err = strconv.Atoi(v)
+			if err != nil || *dst < 0 {
+				return opts, fmt.Errorf("invalid %s: %q", name, v)
+			}
+		}
+	}
+
+	if v := q.Get("min_count"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return opts, fmt.Errorf("invalid min_count: %v", err)
+		}
+		opts.MinCount = int32(n)
+	}
+
+	if v := q.Get("min_score"); v != "" {
+		opts.MinScore, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid min_score: %v", err)
+		}
+	}
+
+	if v := q.Get("exclude"); v != "" {
+		opts.Exclusion, err = recommend.ParseExclusion(v)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if v := q.Get("blocklist"); v != "" {
+		opts.Blocklist = strings.Split(v, ",")
+	}
+
+	if v := q.Get("since"); v != "" {
+		opts.Since, err = parseTime(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		opts.Until, err = parseTime(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+
+	if v := q.Get("half_life"); v != "" {
+		opts.HalfLife, err = time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid half_life: %v", err)
+		}
+	}
+
+	if v := q.Get("show_unavailable"); v != "" {
+		opts.ShowUnavailable, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid show_unavailable: %v", err)
+		}
+	}
+
+	if v := q.Get("no_fallback"); v != "" {
+		opts.NoFallback, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid no_fallback: %v", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// writeResult writes v as JSON, or a 500 when the query failed
+// inventory serves GET and PUT /products/{id}/inventory. A PUT only changes the fields in
+// the body, the others keep their stored values.
+func inventory(w http.ResponseWriter, r *http.Request, store *cayley.Handle, product quad.IRI) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ok, err := model.HasProduct(r.Context(), store, product)
+	if err != nil {
+		writeResult(w, nil, err)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	inv, err := model.LoadInventory(r.Context(), store, product)
+	if err != nil || r.Method == http.MethodGet {
+		writeResult(w, inv, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inv.Product = product
+	writeResult(w, inv, model.UpdateInventory(r.Context(), store, inv))
+}
+
+func writeResult(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
 	_ "github.com/cayleygraph/cayley/graph/bolt"
 	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/reason"
+	"github.com/jtorvald/cayley-demo/social"
 )
 
 func main() {
@@ -17,6 +22,31 @@ func main() {
 	graph.IgnoreMissing = true
 	graph.IgnoreDuplicates = true
 
+	suggest := flag.String("suggest", "", "Suggest people this person may know and exit")
+	limit := flag.Int("limit", 0, "Maximum number of suggestions (0 for all)")
+	predicates := flag.String("predicates", "", "Comma separated predicates to follow (default knows for path, all for expand)")
+	maxDepth := flag.Int("max-depth", social.DefaultMaxDepth, "Maximum number of hops for path and expand")
+	allPaths := flag.Bool("all", false, "Show all shortest paths instead of one")
+	undirected := flag.Bool("undirected", false, "Also follow the predicates backwards")
+	direction := flag.String("direction", "out", "Direction to expand in: out, in or both")
+	isA := flag.String("is-a", "", "List who or what is_a this class, also through other classes, and exit")
+	materialize := flag.Bool("materialize", false, "Write the inferred is_a quads under the inferred label")
+	maxNodes := flag.Int("max-nodes", 0, "Maximum number of nodes to expand to (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [path from to | expand node]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	switch {
+	case flag.NArg() == 0:
+	case flag.Arg(0) == "path" && flag.NArg() == 3:
+	case flag.Arg(0) == "expand" && flag.NArg() == 2:
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// File for your new BoltDB. Use path to regular file and not temporary in the real world
 	t := getTempfileName()
 	fmt.Printf("%v\n", t)
@@ -26,15 +56,70 @@ func main() {
 
 	addQuads(store) // add quads to the graph
 
+	if *materialize {
+		n, err := reason.IsA.Materialize(context.Background(), store)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("materialized %d is_a quads under the %s label\n", n, reason.Label)
+	}
+
+	if *isA != "" {
+		whoIs(store, *isA)
+		return
+	}
+
+	inference, err := social.LoadInference(context.Background(), store)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	switch flag.Arg(0) {
+	case "path":
+		cfg := social.PathConfig{Predicates: parsePredicates(*predicates), MaxDepth: *maxDepth, All: *allPaths, Undirected: *undirected, Inference: inference}
+		shortestPaths(store, flag.Arg(1), flag.Arg(2), cfg)
+		return
+	case "expand":
+		dir, err := social.ParseDirection(*direction)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		cfg := social.ExpandConfig{Direction: dir, Predicates: parsePredicates(*predicates), MaxDepth: *maxDepth, MaxNodes: *maxNodes, Inference: inference}
+		expand(store, flag.Arg(1), cfg)
+		return
+	}
+
+	if *suggest != "" {
+		peopleYouMayKnow(store, *suggest, *limit)
+		return
+	}
+
 	countOuts(store, "robertmeta")
-	lookAtOuts(store, "robertmeta")
-	lookAtIns(store, "robertmeta")
+	lookAtOuts(store, inference, "robertmeta", quad.Raw("follows"))
+	lookAtIns(store, inference, "robertmeta")
 
-	lookAtOuts(store, "jorgent")
-	lookAtIns(store, "jorgent")
+	lookAtOuts(store, inference, "jorgent", quad.Raw("follows"))
+	lookAtIns(store, inference, "jorgent")
 
 	lookAtFriendsOfFriends(store, "barakmich")
+	peopleYouMayKnow(store, "barakmich", 0)
+	shortestPaths(store, "henrocdotnet", "jorgent", social.PathConfig{All: true, Inference: inference})
+	whoIs(store, "hard job without docs")
+
+}
+
+// peopleYouMayKnow prints the friends of friends the person does not know yet, with their mutual friends
+func peopleYouMayKnow(store *cayley.Handle, to string, limit int) {
+	fmt.Printf("\npeopleYouMayKnow for subject (%s):\n", to)
+	fmt.Printf("============================================\n")
 
+	suggestions, err := social.PeopleYouMayKnow(context.Background(), store, quad.Raw(to), limit)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, s := range suggestions {
+		fmt.Printf("%s (%d mutual: %s)\n", s.Person, s.MutualFriends, strings.Join(s.Mutual, ", "))
+	}
 }
 
 func lookAtFriendsOfFriends(store *cayley.Handle, to string) {
@@ -69,8 +154,9 @@ func countOuts(store *cayley.Handle, to string) {
 	fmt.Printf("============================================\n")
 }
 
-// lookAtOuts looks at the outbound links from the "to" node
-func lookAtOuts(store *cayley.Handle, to string) {
+// lookAtOuts looks at the outbound links from the "to" node, one level further along the
+// "further" predicate, and the ones inferred from symmetric and inverse predicates
+func lookAtOuts(store *cayley.Handle, inference *social.Inference, to string, further quad.Value) {
 	p := cayley.StartPath(store, quad.Raw(to)) // start from a single node, but we could start from multiple
 
 	// this gives us a path with all the output predicates from our starting point
@@ -79,21 +165,35 @@ func lookAtOuts(store *cayley.Handle, to string) {
 	fmt.Printf("\nlookAtOuts: subject (%s) -predicate-> object\n", to)
 	fmt.Printf("============================================\n")
 
+	var followed []quad.Value
 	p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
 		fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["object"])
-		if m["predicate"] == quad.Raw("follows") {
+		if m["predicate"] == further {
+			followed = append(followed, m["object"])
+		}
+	})
 
-			p = cayley.StartPath(store, m["object"]).Tag("subject").OutWithTags([]string{"predicate"}).Tag("object")
+	// look one level further along the given predicate, with a path of its own
+	for _, f := range followed {
+		fp := cayley.StartPath(store, f).Tag("subject").OutWithTags([]string{"predicate"}).Tag("object")
+		fp.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
+			fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["object"])
+		})
+	}
 
-			p.Iterate(nil).TagValues(nil, func(m map[string]quad.Value) {
-				fmt.Printf("%s `%s`-> %s\n", m["subject"], m["predicate"], m["object"])
-			})
+	edges, err := inference.Outs(context.Background(), store, quad.Raw(to))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, e := range edges {
+		if e.Inferred {
+			fmt.Printf("%s `%s`-> %s (inferred)\n", e.Subject, e.Predicate, e.Object)
 		}
-	})
+	}
 }
 
-// lookAtIns looks at the inbound links to the "to" node
-func lookAtIns(store *cayley.Handle, to string) {
+// lookAtIns looks at the inbound links to the "to" node, and the ones inferred from symmetric and inverse predicates
+func lookAtIns(store *cayley.Handle, inference *social.Inference, to string) {
 	fmt.Printf("\nlookAtIns: object <-predicate- subject (%s)\n", to)
 	fmt.Printf("=============================================\n")
 
@@ -101,6 +201,94 @@ func lookAtIns(store *cayley.Handle, to string) {
 		fmt.Printf("%s <-`%s` %s\n", m["object"], m["predicate"], m["subject"])
 	})
 
+	edges, err := inference.Ins(context.Background(), store, quad.Raw(to))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, e := range edges {
+		if e.Inferred {
+			fmt.Printf("%s <-`%s` %s (inferred)\n", e.Object, e.Predicate, e.Subject)
+		}
+	}
+}
+
+// shortestPaths prints how one person connects to another
+func shortestPaths(store *cayley.Handle, from, to string, cfg social.PathConfig) {
+	fmt.Printf("\nshortestPaths from (%s) to (%s):\n", from, to)
+	fmt.Printf("============================================\n")
+
+	paths, err := social.ShortestPaths(context.Background(), store, quad.Raw(from), quad.Raw(to), cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(paths) == 0 {
+		fmt.Println("not connected")
+		return
+	}
+	for _, p := range paths {
+		fmt.Printf("%d degrees: %s", len(p), from)
+		for _, s := range p {
+			if s.Inverse {
+				fmt.Printf(" <-`%s` %s", s.Predicate, s.To)
+			} else {
+				fmt.Printf(" `%s`-> %s", s.Predicate, s.To)
+			}
+			if s.Inferred {
+				fmt.Print(" (inferred)")
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// whoIs prints everybody and everything that is_a class, directly or through another class
+func whoIs(store *cayley.Handle, class string) {
+	fmt.Printf("\nwhoIs (%s):\n", class)
+	fmt.Printf("============================================\n")
+
+	instances, err := reason.IsA.InstancesOf(context.Background(), store, quad.Raw(class))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, v := range instances {
+		fmt.Printf("%s `is_a`-> %s\n", v, class)
+	}
+}
+
+// expand prints the neighbourhood of a node
+func expand(store *cayley.Handle, node string, cfg social.ExpandConfig) {
+	fmt.Printf("\nexpand (%s) %s up to %d hops:\n", node, cfg.Direction, cfg.MaxDepth)
+	fmt.Printf("============================================\n")
+
+	g, err := social.Expand(context.Background(), store, quad.Raw(node), cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, n := range g.Nodes {
+		fmt.Printf("%d %s\n", g.Depth[n], n)
+	}
+	for _, e := range g.Edges {
+		if e.Inferred {
+			fmt.Printf("%s `%s`-> %s (inferred)\n", e.Subject, e.Predicate, e.Object)
+		} else {
+			fmt.Printf("%s `%s`-> %s\n", e.Subject, e.Predicate, e.Object)
+		}
+	}
+	if g.Truncated {
+		fmt.Println("stopped at the node budget")
+	}
+}
+
+// parsePredicates splits a comma separated list of predicates, an empty list gives none
+func parsePredicates(s string) []quad.Value {
+	if s == "" {
+		return nil
+	}
+	var predicates []quad.Value
+	for _, pred := range strings.Split(s, ",") {
+		predicates = append(predicates, quad.Raw(pred))
+	}
+	return predicates
 }
 
 func initializeAndOpenGraph(atLoc string) *cayley.Handle {
@@ -163,6 +351,11 @@ func addQuads(store *cayley.Handle) {
 	//store.AddQuad(quad.MakeRaw("632372a5-1085-4e63-a06c-79a6a46fdcea", "follows_to", "robertmeta", "demo graph"))
 	//store.AddQuad(quad.MakeRaw("632372a5-1085-4e63-a06c-79a6a46fdcea", "follows_created_at", "2017-03-20 20:58:00", "demo graph"))
 
+	// predicate rules, the edges they imply are inferred at query time instead of stored
+	store.AddQuad(quad.MakeRaw("drinks_with", "type", "symmetric_property", "demo schema"))
+	store.AddQuad(quad.MakeRaw("works_with", "type", "symmetric_property", "demo schema"))
+	store.AddQuad(quad.MakeRaw("follows_from", "inverse_of", "follows_to", "demo schema"))
+
 	store.AddQuad(quad.MakeRaw("cayley advocate", "is_a", "hard job without docs", "demo graph"))
 	store.AddQuad(quad.MakeRaw("cayley codign machine", "is_a", "", "demo graph"))
 }
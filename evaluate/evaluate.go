@@ -0,0 +1,224 @@
+// Package evaluate measures the quality of recommendations offline, by holding
+// out purchases from the graph and checking whether they get recommended.
+package evaluate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	_ "github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/recommend"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Split names the way purchases are divided into training and test data
+type Split string
+
+const (
+	// SplitLeaveOneOut holds out one random purchase of every customer with at least two purchases
+	SplitLeaveOneOut Split = "loo"
+	// SplitTime holds out all purchases made at or after the cutoff, purchases without a time
+	// are kept for training
+	SplitTime Split = "time"
+)
+
+// Config configures the holdout split
+type Config struct {
+	Split Split
+	// Cutoff is the time SplitTime splits at
+	Cutoff time.Time
+	// Seed makes SplitLeaveOneOut pick the same purchases every run
+	Seed int64
+}
+
+// Recommender returns the recommendations for a customer, best first
+type Recommender func(ctx context.Context, store *cayley.Handle, customer quad.Value) (recommend.ProductRecommendations, error)
+
+// Report holds the metrics of one recommender, averaged over the test customers
+type Report struct {
+	Name      string  `json:"name"`
+	Split     Split   `json:"split"`
+	K         int     `json:"k"`
+	Customers int     `json:"customers"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	MAP       float64 `json:"map"`
+	NDCG      float64 `json:"ndcg"`
+	Coverage  float64 `json:"coverage"`
+}
+
+// Holdout is a training graph without the held out purchases, and the products held out per customer
+type Holdout struct {
+	Train *cayley.Handle
+	// Test holds the held out product ids per customer
+	Test  map[quad.Value]map[string]struct{}
+	split Split
+	// catalog is the number of products in the store
+	catalog int
+}
+
+// purchaseRow is a client -has_purchase-> purchase edge with what and when was bought
+type purchaseRow struct {
+	customer, purchase quad.Value
+	product            string
+	at                 time.Time
+}
+
+// NewHoldout splits the purchases in the store and copies everything but the held out
+// client -has_purchase-> purchase quads, with their labels, to an in-memory training graph
+func NewHoldout(ctx context.Context, store *cayley.Handle, cfg Config) (*Holdout, error) {
+	var rows []purchaseRow
+	p := cayley.StartPath(store).Tag("customer").Out(vocab.HasPurchase).Tag("purchase").SaveOptional(vocab.At, "at").Out(vocab.PurchaseOf).Tag("product")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		r := purchaseRow{customer: m["customer"], purchase: m["purchase"], product: vocab.String(m["product"])}
+		if at, ok := m["at"].(quad.Time); ok {
+			r.at = time.Time(at)
+		}
+		rows = append(rows, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// sort so the split does not depend on the iteration order of the store
+	sort.Slice(rows, func(i, j int) bool {
+		if a, b := vocab.String(rows[i].customer), vocab.String(rows[j].customer); a != b {
+			return a < b
+		}
+		return vocab.String(rows[i].purchase) < vocab.String(rows[j].purchase)
+	})
+
+	held := make(map[quad.Value]bool)
+	h := &Holdout{Test: make(map[quad.Value]map[string]struct{}), split: cfg.Split}
+	hold := func(r purchaseRow) {
+		held[r.purchase] = true
+		if h.Test[r.customer] == nil {
+			h.Test[r.customer] = make(map[string]struct{})
+		}
+		h.Test[r.customer][r.product] = struct{}{}
+	}
+
+	switch cfg.Split {
+	case "":
+		h.split = SplitLeaveOneOut
+		fallthrough
+	case SplitLeaveOneOut:
+		byCustomer := make(map[quad.Value][]purchaseRow)
+		var customers []quad.Value
+		for _, r := range rows {
+			if byCustomer[r.customer] == nil {
+				customers = append(customers, r.customer)
+			}
+			byCustomer[r.customer] = append(byCustomer[r.customer], r)
+		}
+		rnd := rand.New(rand.NewSource(cfg.Seed))
+		for _, c := range customers {
+			if rs := byCustomer[c]; len(rs) >= 2 {
+				hold(rs[rnd.Intn(len(rs))])
+			}
+		}
+	case SplitTime:
+		if cfg.Cutoff.IsZero() {
+			return nil, fmt.Errorf("time split needs a cutoff")
+		}
+		for _, r := range rows {
+			if !r.at.Before(cfg.Cutoff) {
+				hold(r)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown split %q", cfg.Split)
+	}
+
+	h.Train, err = cayley.NewMemoryGraph()
+	if err != nil {
+		return nil, err
+	}
+	// copy the quads with their labels, so label-sensitive code behaves as on the store
+	w := graph.NewWriter(h.Train)
+	it := store.QuadsAllIterator()
+	for err == nil && it.Next(ctx) {
+		q := store.Quad(it.Result())
+		if q.Predicate == vocab.HasPurchase && held[q.Object] {
+			continue
+		}
+		err = w.WriteQuad(q)
+	}
+	if err == nil {
+		err = it.Err()
+	}
+	it.Close()
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := cayley.StartPath(store, vocab.ClassProduct).In(vocab.Type).Iterate(ctx).AllValues(nil)
+	if err != nil {
+		return nil, err
+	}
+	h.catalog = len(products)
+
+	return h, nil
+}
+
+// Evaluate runs the recommender for every test customer on the training graph and
+// scores its top k against the held out products
+func (h *Holdout) Evaluate(ctx context.Context, name string, rec Recommender, k int) (Report, error) {
+	report := Report{Name: name, Split: h.split, K: k}
+	if k <= 0 {
+		return report, fmt.Errorf("k must be positive, got %d", k)
+	}
+	recommended := make(map[string]struct{})
+
+	for customer, relevant := range h.Test {
+		recs, err := rec(ctx, h.Train, customer)
+		if err != nil {
+			return report, err
+		}
+		if len(recs) > k {
+			recs = recs[:k]
+		}
+
+		var hits int
+		var ap, dcg float64
+		for i, r := range recs {
+			recommended[r.ProductID] = struct{}{}
+			if _, ok := relevant[r.ProductID]; ok {
+				hits++
+				ap += float64(hits) / float64(i+1)
+				dcg += 1 / math.Log2(float64(i+2))
+			}
+		}
+
+		var idcg float64
+		for i := 0; i < len(relevant) && i < k; i++ {
+			idcg += 1 / math.Log2(float64(i+2))
+		}
+
+		report.Customers++
+		report.Precision += float64(hits) / float64(k)
+		report.Recall += float64(hits) / float64(len(relevant))
+		report.MAP += ap / math.Min(float64(len(relevant)), float64(k))
+		report.NDCG += dcg / idcg
+	}
+
+	if n := float64(report.Customers); n > 0 {
+		report.Precision /= n
+		report.Recall /= n
+		report.MAP /= n
+		report.NDCG /= n
+	}
+	if h.catalog > 0 {
+		report.Coverage = float64(len(recommended)) / float64(h.catalog)
+	}
+	return report, nil
+}
@@ -0,0 +1,159 @@
+package evaluate
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/recommend"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+func set(ids ...string) map[string]struct{} {
+	s := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		s[id] = struct{}{}
+	}
+	return s
+}
+
+func TestEvaluate(t *testing.T) {
+	train, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Holdout{
+		Train: train,
+		Test: map[quad.Value]map[string]struct{}{
+			quad.IRI("ann"): set("p1", "p2"),
+			quad.IRI("bob"): set("p4"),
+		},
+		split:   SplitLeaveOneOut,
+		catalog: 10,
+	}
+	recommended := map[quad.Value][]string{
+		// hits at ranks 1 and 3
+		quad.IRI("ann"): {"p1", "p3", "p2"},
+		// the hit at rank 4 falls outside k
+		quad.IRI("bob"): {"p5", "p6", "p7", "p4"},
+	}
+	rec := func(ctx context.Context, store *cayley.Handle, customer quad.Value) (recommend.ProductRecommendations, error) {
+		var recs recommend.ProductRecommendations
+		for _, id := range recommended[customer] {
+			recs = append(recs, recommend.ProductRecommendation{ProductID: id})
+		}
+		return recs, nil
+	}
+
+	report, err := h.Evaluate(context.TODO(), "fixed", rec, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Name != "fixed" || report.Split != SplitLeaveOneOut || report.K != 3 || report.Customers != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// ann has hits at ranks 1 and 3 out of two relevant products, bob has none
+	metrics := []struct {
+		name      string
+		got, want float64
+	}{
+		{"precision", report.Precision, (2.0 / 3) / 2},
+		{"recall", report.Recall, 1.0 / 2},
+		{"map", report.MAP, ((1 + 2.0/3) / 2) / 2},
+		{"ndcg", report.NDCG, ((1 + 1/math.Log2(4)) / (1 + 1/math.Log2(3))) / 2},
+		{"coverage", report.Coverage, 6.0 / 10},
+	}
+	for _, m := range metrics {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", m.name, m.got, m.want)
+		}
+	}
+
+	if _, err := h.Evaluate(context.TODO(), "fixed", rec, 0); err == nil {
+		t.Error("expected an error for k = 0")
+	}
+}
+
+func TestNewHoldout(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day int) time.Time {
+		return time.Date(2017, 1, day, 12, 0, 0, 0, time.UTC)
+	}
+	quads := []quad.Quad{
+		quad.Make(quad.IRI("p1"), vocab.Type, vocab.ClassProduct, "catalog"),
+		quad.Make(quad.IRI("p2"), vocab.Type, vocab.ClassProduct, "catalog"),
+		quad.Make(quad.IRI("ann"), vocab.Firstname, "Ann", "crm"),
+		quad.Make(quad.IRI("ann"), vocab.HasPurchase, quad.IRI("o1"), "sales"),
+		quad.Make(quad.IRI("o1"), vocab.PurchaseOf, quad.IRI("p1"), "sales"),
+		quad.Make(quad.IRI("o1"), vocab.At, quad.Time(at(1)), "sales"),
+		quad.Make(quad.IRI("ann"), vocab.HasPurchase, quad.IRI("o2"), "sales"),
+		quad.Make(quad.IRI("o2"), vocab.PurchaseOf, quad.IRI("p2"), "sales"),
+		quad.Make(quad.IRI("o2"), vocab.At, quad.Time(at(20)), "sales"),
+		// bought at an unknown time, kept for training by the time split
+		quad.Make(quad.IRI("bob"), vocab.HasPurchase, quad.IRI("o3"), "sales"),
+		quad.Make(quad.IRI("o3"), vocab.PurchaseOf, quad.IRI("p1"), "sales"),
+		quad.Make(quad.IRI("bob"), vocab.HasPurchase, quad.IRI("o4"), "sales"),
+		quad.Make(quad.IRI("o4"), vocab.PurchaseOf, quad.IRI("p2"), "sales"),
+		quad.Make(quad.IRI("o4"), vocab.At, quad.Time(at(2)), "sales"),
+		quad.Make(quad.IRI("p2"), vocab.Stock, 3, nil),
+	}
+	if err := store.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHoldout(context.TODO(), store, Config{Split: SplitTime, Cutoff: at(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTest := map[quad.Value]map[string]struct{}{quad.IRI("ann"): set("p2")}
+	if !reflect.DeepEqual(h.Test, wantTest) {
+		t.Errorf("test %v, want %v", h.Test, wantTest)
+	}
+	if h.catalog != 2 {
+		t.Errorf("catalog %d, want 2", h.catalog)
+	}
+
+	// everything but the held out purchase edge is copied with its label
+	want := make(map[string]bool)
+	for _, q := range quads {
+		if q.Predicate == vocab.HasPurchase && q.Object == quad.IRI("o2") {
+			continue
+		}
+		want[q.NQuad()] = true
+	}
+	got := make(map[string]bool)
+	it := h.Train.QuadsAllIterator()
+	defer it.Close()
+	for it.Next(context.TODO()) {
+		got[h.Train.Quad(it.Result()).NQuad()] = true
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("training quads %v, want %v", got, want)
+	}
+
+	// bob has two purchases when the undated one counts
+	h, err = NewHoldout(context.TODO(), store, Config{Split: SplitLeaveOneOut})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Test[quad.IRI("ann")]) != 1 || len(h.Test[quad.IRI("bob")]) != 1 {
+		t.Errorf("leave one out test %v, want a purchase of ann and bob", h.Test)
+	}
+
+	if _, err := NewHoldout(context.TODO(), store, Config{Split: SplitTime}); err == nil {
+		t.Error("expected an error for a time split without a cutoff")
+	}
+}
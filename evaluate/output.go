@@ -0,0 +1,26 @@
+package evaluate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteTable writes the reports as an aligned table
+func WriteTable(w io.Writer, reports []Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "recommender\tsplit\tcustomers\tprecision@k\trecall@k\tMAP@k\tNDCG@k\tcoverage\t\n")
+	for _, r := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t\n",
+			r.Name, r.Split, r.Customers, r.Precision, r.Recall, r.MAP, r.NDCG, r.Coverage)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the reports as an indented JSON array
+func WriteJSON(w io.Writer, reports []Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// LabelInventory is the label the inventory of the products is stored under
+const LabelInventory = "inventory"
+
+// Inventory is the stock and status of a product, stored as the stock, available and
+// discontinued predicates of the product node
+type Inventory struct {
+	Product quad.IRI `json:"product_id"`
+	// Stock is the number of items in stock, nil when the stock is not tracked
+	Stock *int `json:"stock,omitempty"`
+	// Available is false when the product can not be ordered for now
+	Available bool `json:"available"`
+	// Discontinued is true when the product will not come back
+	Discontinued bool `json:"discontinued"`
+}
+
+// InStock is false when the product is unavailable, discontinued or its tracked stock ran out
+func (inv Inventory) InStock() bool {
+	return inv.Available && !inv.Discontinued && (inv.Stock == nil || *inv.Stock > 0)
+}
+
+// UpdateInventory replaces the inventory of the product in one transaction. The old stock,
+// available and discontinued quads are removed under whatever label they were stored.
+func UpdateInventory(ctx context.Context, store *cayley.Handle, inv Inventory) error {
+	tx := graph.NewTransaction()
+
+	if product := store.ValueOf(inv.Product); product != nil {
+		it := store.QuadIterator(quad.Subject, product)
+		for it.Next(ctx) {
+			q := store.Quad(it.Result())
+			switch q.Predicate {
+			case vocab.Stock, vocab.Available, vocab.Discontinued:
+				tx.RemoveQuad(q)
+			}
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.AddQuad(quad.Make(inv.Product, vocab.Available, quad.Bool(inv.Available), LabelInventory))
+	tx.AddQuad(quad.Make(inv.Product, vocab.Discontinued, quad.Bool(inv.Discontinued), LabelInventory))
+	if inv.Stock != nil {
+		tx.AddQuad(quad.Make(inv.Product, vocab.Stock, quad.Int(*inv.Stock), LabelInventory))
+	}
+
+	return store.ApplyDeltas(tx.Deltas, graph.IgnoreOpts{})
+}
+
+// LoadInventory loads the inventory of a product. Products without inventory are available
+// and their stock is not tracked.
+func LoadInventory(ctx context.Context, store *cayley.Handle, product quad.IRI) (Inventory, error) {
+	inv := Inventory{Product: product, Available: true}
+	p := cayley.StartPath(store, product).OutWithTags([]string{"predicate"}, vocab.Stock, vocab.Available, vocab.Discontinued)
+	err := p.Tag("value").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		switch m["predicate"] {
+		case vocab.Stock:
+			if n, ok := m["value"].(quad.Int); ok {
+				stock := int(n)
+				inv.Stock = &stock
+			}
+		case vocab.Available:
+			if b, ok := m["value"].(quad.Bool); ok {
+				inv.Available = bool(b)
+			}
+		case vocab.Discontinued:
+			if b, ok := m["value"].(quad.Bool); ok {
+				inv.Discontinued = bool(b)
+			}
+		}
+	})
+	return inv, err
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+func TestUpdateInventory(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	walky := quad.IRI("walky-talky")
+	err = store.AddQuadSet([]quad.Quad{
+		quad.Make(walky, vocab.Label, "Walky talky", LabelCatalog),
+		// stored under other labels than the inventory one
+		quad.Make(walky, vocab.Stock, 0, LabelCatalog),
+		quad.Make(walky, vocab.Available, true, nil),
+		quad.Make(walky, vocab.Discontinued, false, LabelInventory),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stock := 12
+	updates := []Inventory{
+		{Product: walky, Stock: &stock, Available: true},
+		// the same again, nothing changes
+		{Product: walky, Stock: &stock, Available: true},
+		// stock no longer tracked
+		{Product: walky, Discontinued: true},
+	}
+	tracked := []string{
+		quad.Make(walky, vocab.Available, true, LabelInventory).NQuad(),
+		quad.Make(walky, vocab.Discontinued, false, LabelInventory).NQuad(),
+		quad.Make(walky, vocab.Label, "Walky talky", LabelCatalog).NQuad(),
+		quad.Make(walky, vocab.Stock, 12, LabelInventory).NQuad(),
+	}
+	discontinued := []string{
+		quad.Make(walky, vocab.Available, false, LabelInventory).NQuad(),
+		quad.Make(walky, vocab.Discontinued, true, LabelInventory).NQuad(),
+		quad.Make(walky, vocab.Label, "Walky talky", LabelCatalog).NQuad(),
+	}
+	want := [][]string{tracked, tracked, discontinued}
+
+	for i, inv := range updates {
+		if err := UpdateInventory(context.TODO(), store, inv); err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+
+		var got []string
+		it := store.QuadsAllIterator()
+		for it.Next(context.TODO()) {
+			got = append(got, store.Quad(it.Result()).NQuad())
+		}
+		it.Close()
+		sort.Strings(got)
+		sort.Strings(want[i])
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("update %d: quads %v, want %v", i, got, want[i])
+		}
+
+		loaded, err := LoadInventory(context.TODO(), store, walky)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, inv) {
+			t.Errorf("update %d: loaded %+v, want %+v", i, loaded, inv)
+		}
+	}
+}
+
+func TestUpdateInventoryNewProduct(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := Inventory{Product: quad.IRI("pen"), Available: true}
+	if err := UpdateInventory(context.TODO(), store, inv); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadInventory(context.TODO(), store, inv.Product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, inv) {
+		t.Errorf("loaded %+v, want %+v", loaded, inv)
+	}
+}
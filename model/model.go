@@ -0,0 +1,150 @@
+// Package model maps the clients, products and product groups of the
+// recommendations demo to Go structs, using the cayley schema package.
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/cayley/schema"
+	"github.com/jtorvald/cayley-demo/vocab"
+	"github.com/satori/go.uuid"
+)
+
+// labels the entities are stored under
+const (
+	LabelCatalog = "catalog"
+	LabelCRM     = "crm"
+	LabelSales   = "sales"
+)
+
+// Client is a person who placed an order
+type Client struct {
+	rdfType   struct{} `quad:"type > client"`
+	ID        quad.IRI `quad:"@id"`
+	Firstname string   `quad:"firstname"`
+	Lastname  string   `quad:"lastname"`
+}
+
+// Product is a store product
+type Product struct {
+	rdfType    struct{}  `quad:"type > product"`
+	ID         quad.IRI  `quad:"@id"`
+	Label      string    `quad:"label"`
+	Desc       string    `quad:"desc"`
+	Price      float64   `quad:"price"`
+	Consumable bool      `quad:"consumable,optional"`
+	Group      quad.IRI  `quad:"in_group,optional"`
+	Added      time.Time `quad:"added,optional"`
+}
+
+// ProductGroup is a group of products
+type ProductGroup struct {
+	rdfType struct{} `quad:"type > product_group"`
+	ID      quad.IRI `quad:"@id"`
+	Label   string   `quad:"label"`
+	Desc    string   `quad:"desc"`
+}
+
+// Purchase is a line item of an order: a quantity of a product bought by a client at a time.
+// It is a node of its own, linked from the client with client -has_purchase-> purchase.
+type Purchase struct {
+	rdfType   struct{}  `quad:"type > purchase"`
+	ID        quad.IRI  `quad:"@id"`
+	Client    quad.IRI  // not mapped, stored as the client -has_purchase-> purchase quad
+	Product   quad.IRI  `quad:"purchase_of"`
+	At        time.Time `quad:"at"`
+	Quantity  int       `quad:"quantity"`
+	UnitPrice float64   `quad:"unit_price"`
+}
+
+// SaveClient writes the client under the crm label
+func SaveClient(w quad.Writer, c Client) error {
+	_, err := schema.WriteAsQuads(vocab.Labeled(w, LabelCRM), c)
+	return err
+}
+
+// SaveProduct writes the product under the catalog label
+func SaveProduct(w quad.Writer, p Product) error {
+	_, err := schema.WriteAsQuads(vocab.Labeled(w, LabelCatalog), p)
+	return err
+}
+
+// SaveProductGroup writes the product group under the catalog label
+func SaveProductGroup(w quad.Writer, g ProductGroup) error {
+	_, err := schema.WriteAsQuads(vocab.Labeled(w, LabelCatalog), g)
+	return err
+}
+
+// SavePurchase writes the purchase under the sales label. A new id is
+// generated when the purchase has none.
+func SavePurchase(w quad.Writer, p Purchase) error {
+	if p.ID == "" {
+		p.ID = quad.IRI(uuid.NewV4().String())
+	}
+	if err := w.WriteQuad(quad.Make(p.Client, vocab.HasPurchase, p.ID, LabelSales)); err != nil {
+		return err
+	}
+	_, err := schema.WriteAsQuads(vocab.Labeled(w, LabelSales), p)
+	return err
+}
+
+// LoadClient loads a single client
+func LoadClient(ctx context.Context, store *cayley.Handle, id quad.IRI) (Client, error) {
+	var c Client
+	err := schema.LoadTo(ctx, store, &c, id)
+	return c, err
+}
+
+// LoadProduct loads a single product
+func LoadProduct(ctx context.Context, store *cayley.Handle, id quad.IRI) (Product, error) {
+	var p Product
+	err := schema.LoadTo(ctx, store, &p, id)
+	return p, err
+}
+
+// HasProduct reports whether the id is stored as a product
+func HasProduct(ctx context.Context, store *cayley.Handle, id quad.IRI) (bool, error) {
+	n, err := cayley.StartPath(store, id).Has(vocab.Type, vocab.ClassProduct).Iterate(ctx).Count()
+	return n > 0, err
+}
+
+// LoadProductGroup loads a single product group
+func LoadProductGroup(ctx context.Context, store *cayley.Handle, id quad.IRI) (ProductGroup, error) {
+	var g ProductGroup
+	err := schema.LoadTo(ctx, store, &g, id)
+	return g, err
+}
+
+// LoadClients loads all clients
+func LoadClients(ctx context.Context, store *cayley.Handle) ([]Client, error) {
+	var clients []Client
+	err := schema.LoadTo(ctx, store, &clients)
+	return clients, err
+}
+
+// LoadProducts loads all products
+func LoadProducts(ctx context.Context, store *cayley.Handle) ([]Product, error) {
+	var products []Product
+	err := schema.LoadTo(ctx, store, &products)
+	return products, err
+}
+
+// LoadPurchases loads the purchases of a client
+func LoadPurchases(ctx context.Context, store *cayley.Handle, client quad.IRI) ([]Purchase, error) {
+	ids, err := cayley.StartPath(store, client).Out(vocab.HasPurchase).Iterate(ctx).AllValues(nil)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var purchases []Purchase
+	if err := schema.LoadTo(ctx, store, &purchases, ids...); err != nil {
+		return nil, err
+	}
+	for i := range purchases {
+		purchases[i].Client = client
+	}
+	return purchases, nil
+}
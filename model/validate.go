@@ -0,0 +1,192 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// ViolationKind is the kind of problem found by Validate
+type ViolationKind string
+
+const (
+	// MissingProperty means a property declared with hasProperty is not set
+	MissingProperty ViolationKind = "missing_property"
+	// UndeclaredProperty means a property is set that its class does not declare
+	UndeclaredProperty ViolationKind = "undeclared_property"
+	// UnknownClass means an entity has a type that is not registered as a class
+	UnknownClass ViolationKind = "unknown_class"
+	// InvalidValue means a value does not match the range declared for its property
+	InvalidValue ViolationKind = "invalid_value"
+)
+
+// Violation is an entity that does not match the class it claims to be
+type Violation struct {
+	Kind     ViolationKind `json:"kind"`
+	Entity   string        `json:"entity"`
+	Class    string        `json:"class"`
+	Property string        `json:"property,omitempty"`
+	Value    string        `json:"value,omitempty"`
+}
+
+func (v Violation) String() string {
+	switch v.Kind {
+	case MissingProperty:
+		return fmt.Sprintf("%s (%s): missing property %s", v.Entity, v.Class, v.Property)
+	case UndeclaredProperty:
+		return fmt.Sprintf("%s (%s): property %s is not declared on the class", v.Entity, v.Class, v.Property)
+	case UnknownClass:
+		return fmt.Sprintf("%s: unknown class %s", v.Entity, v.Class)
+	case InvalidValue:
+		return fmt.Sprintf("%s (%s): property %s has invalid value %s", v.Entity, v.Class, v.Property, v.Value)
+	}
+	return fmt.Sprintf("%s (%s): %s", v.Entity, v.Class, v.Kind)
+}
+
+// class is the metadata registered for a class: its properties and whether they are required
+type class struct {
+	properties map[quad.IRI]bool
+}
+
+// Validate checks every typed entity in the store against the classes registered with
+// `type class`, their hasProperty (required) and hasOptionalProperty predicates and the
+// range declared for each property (string, float, int, bool, time or iri).
+//
+// Like rdfs:range, a range is declared on the property itself and not per class: it holds
+// for every class that declares the property. When a property has several ranges one of
+// them is used.
+func Validate(ctx context.Context, store *cayley.Handle) ([]Violation, error) {
+	classes := make(map[quad.IRI]*class)
+	err := cayley.StartPath(store, vocab.ClassClass).In(vocab.Type).Iterate(ctx).EachValue(nil, func(v quad.Value) {
+		if iri, ok := v.(quad.IRI); ok {
+			classes[iri] = &class{properties: make(map[quad.IRI]bool)}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make(map[quad.IRI]quad.IRI)
+	for iri, c := range classes {
+		p := cayley.StartPath(store, iri).OutWithTags([]string{"predicate"}, vocab.HasProperty, vocab.HasOptionalProperty).Tag("property")
+		err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+			if property, ok := m["property"].(quad.IRI); ok {
+				c.properties[property] = m["predicate"] == vocab.HasProperty
+				ranges[property] = ""
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for property := range ranges {
+		err := cayley.StartPath(store, property).Out(vocab.Range).Iterate(ctx).EachValue(nil, func(v quad.Value) {
+			if iri, ok := v.(quad.IRI); ok {
+				ranges[property] = iri
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type typed struct {
+		entity quad.Value
+		class  quad.IRI
+	}
+	var entities []typed
+	err = cayley.StartPath(store).Tag("entity").Out(vocab.Type).Tag("class").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		iri, _ := m["class"].(quad.IRI)
+		if iri != vocab.ClassClass {
+			entities = append(entities, typed{entity: m["entity"], class: iri})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+	for _, e := range entities {
+		v := Violation{Entity: vocab.String(e.entity), Class: string(e.class)}
+
+		c, ok := classes[e.class]
+		if !ok {
+			v.Kind = UnknownClass
+			violations = append(violations, v)
+			continue
+		}
+
+		seen := make(map[quad.IRI]bool)
+		p := cayley.StartPath(store, e.entity).OutWithTags([]string{"predicate"}).Tag("object")
+		err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+			property, _ := m["predicate"].(quad.IRI)
+			if property == vocab.Type {
+				return
+			}
+			seen[property] = true
+
+			if _, declared := c.properties[property]; !declared {
+				v.Kind, v.Property, v.Value = UndeclaredProperty, string(property), ""
+				violations = append(violations, v)
+				return
+			}
+			if !inRange(m["object"], ranges[property]) {
+				v.Kind, v.Property, v.Value = InvalidValue, string(property), vocab.String(m["object"])
+				violations = append(violations, v)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for property, required := range c.properties {
+			if required && !seen[property] {
+				v.Kind, v.Property, v.Value = MissingProperty, string(property), ""
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Entity != violations[j].Entity {
+			return violations[i].Entity < violations[j].Entity
+		}
+		return violations[i].Property < violations[j].Property
+	})
+	return violations, nil
+}
+
+// inRange reports whether v is a value of the named range, an empty range allows anything
+func inRange(v quad.Value, r quad.IRI) bool {
+	switch r {
+	case "":
+		return true
+	case "string":
+		_, ok := v.(quad.String)
+		return ok
+	case "float":
+		switch v.(type) {
+		case quad.Float, quad.Int:
+			return true
+		}
+		return false
+	case "int":
+		_, ok := v.(quad.Int)
+		return ok
+	case "bool":
+		_, ok := v.(quad.Bool)
+		return ok
+	case "time":
+		_, ok := v.(quad.Time)
+		return ok
+	case "iri":
+		_, ok := v.(quad.IRI)
+		return ok
+	}
+	return false
+}
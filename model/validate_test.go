@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+func TestValidate(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool, group := quad.IRI("tool"), quad.IRI("tool_group")
+	name, price, sharp := quad.IRI("name"), quad.IRI("price"), quad.IRI("sharp")
+	err = store.AddQuadSet([]quad.Quad{
+		quad.Make(tool, vocab.Type, vocab.ClassClass, nil),
+		quad.Make(tool, vocab.HasProperty, name, nil),
+		quad.Make(tool, vocab.HasProperty, price, nil),
+		quad.Make(tool, vocab.HasOptionalProperty, sharp, nil),
+		quad.Make(group, vocab.Type, vocab.ClassClass, nil),
+		quad.Make(group, vocab.HasProperty, name, nil),
+		quad.Make(name, vocab.Range, quad.IRI("string"), nil),
+		quad.Make(price, vocab.Range, quad.IRI("float"), nil),
+		quad.Make(sharp, vocab.Range, quad.IRI("bool"), nil),
+
+		// valid, an int is a float too
+		quad.Make(quad.IRI("hammer"), vocab.Type, tool, nil),
+		quad.Make(quad.IRI("hammer"), name, "Hammer", nil),
+		quad.Make(quad.IRI("hammer"), price, 12, nil),
+		quad.Make(quad.IRI("knife"), vocab.Type, tool, nil),
+		quad.Make(quad.IRI("knife"), name, "Knife", nil),
+		quad.Make(quad.IRI("knife"), price, 4.5, nil),
+		quad.Make(quad.IRI("knife"), sharp, true, nil),
+		quad.Make(quad.IRI("hand_tools"), vocab.Type, group, nil),
+		quad.Make(quad.IRI("hand_tools"), name, "Hand tools", nil),
+
+		quad.Make(quad.IRI("saw"), vocab.Type, tool, nil),
+		quad.Make(quad.IRI("saw"), name, "Saw", nil),
+		quad.Make(quad.IRI("drill"), vocab.Type, tool, nil),
+		quad.Make(quad.IRI("drill"), name, "Drill", nil),
+		quad.Make(quad.IRI("drill"), price, 80.0, nil),
+		quad.Make(quad.IRI("drill"), quad.IRI("color"), "red", nil),
+		quad.Make(quad.IRI("axe"), vocab.Type, tool, nil),
+		quad.Make(quad.IRI("axe"), name, "Axe", nil),
+		quad.Make(quad.IRI("axe"), price, "cheap", nil),
+		quad.Make(quad.IRI("axe"), sharp, "very", nil),
+		// the range of name holds for groups as well
+		quad.Make(quad.IRI("power_tools"), vocab.Type, group, nil),
+		quad.Make(quad.IRI("power_tools"), name, 42, nil),
+		quad.Make(quad.IRI("toolbox"), vocab.Type, quad.IRI("container"), nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Violation{
+		{Kind: InvalidValue, Entity: "axe", Class: "tool", Property: "price", Value: "cheap"},
+		{Kind: InvalidValue, Entity: "axe", Class: "tool", Property: "sharp", Value: "very"},
+		{Kind: UndeclaredProperty, Entity: "drill", Class: "tool", Property: "color"},
+		{Kind: InvalidValue, Entity: "power_tools", Class: "tool_group", Property: "name", Value: quad.Int(42).String()},
+		{Kind: MissingProperty, Entity: "saw", Class: "tool", Property: "price"},
+		{Kind: UnknownClass, Entity: "toolbox", Class: "container"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestViolationString(t *testing.T) {
+	tests := []struct {
+		v    Violation
+		want string
+	}{
+		{Violation{Kind: MissingProperty, Entity: "saw", Class: "tool", Property: "price"}, "saw (tool): missing property price"},
+		{Violation{Kind: UndeclaredProperty, Entity: "drill", Class: "tool", Property: "color"}, "drill (tool): property color is not declared on the class"},
+		{Violation{Kind: UnknownClass, Entity: "toolbox", Class: "container"}, "toolbox: unknown class container"},
+		{Violation{Kind: InvalidValue, Entity: "axe", Class: "tool", Property: "price", Value: "cheap"}, "axe (tool): property price has invalid value cheap"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package reason answers class membership questions with RDFS style inference: types
+// are inherited along transitive subclass edges.
+//
+//	robertmeta -is_a-> "cayley advocate" -is_a-> "hard job without docs"
+//
+// makes robertmeta a "hard job without docs". The inference runs at query time, or
+// Materialize writes the inferred type quads under a label of their own.
+package reason
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Label is the label Materialize writes the inferred quads under
+const Label = "inferred"
+
+// Vocabulary names the predicates that relate instances to classes and classes to
+// their superclasses. They may be the same predicate.
+type Vocabulary struct {
+	Type       quad.Value
+	SubClassOf quad.Value
+}
+
+var (
+	// IsA is the vocabulary of the social demo, where is_a is used for both
+	IsA = Vocabulary{Type: quad.Raw("is_a"), SubClassOf: quad.Raw("is_a")}
+	// RDFS is the vocabulary of the recommendations demo
+	RDFS = Vocabulary{Type: vocab.Type, SubClassOf: quad.IRI("subClassOf")}
+)
+
+// Superclasses returns the classes the class is a subclass of, directly or not
+func (v Vocabulary) Superclasses(ctx context.Context, store *cayley.Handle, class quad.Value) ([]quad.Value, error) {
+	return closure(ctx, store, []quad.Value{class}, v.SubClassOf, true)
+}
+
+// Subclasses returns the classes that are a subclass of the class, directly or not
+func (v Vocabulary) Subclasses(ctx context.Context, store *cayley.Handle, class quad.Value) ([]quad.Value, error) {
+	return closure(ctx, store, []quad.Value{class}, v.SubClassOf, false)
+}
+
+// TypesOf returns the types of the node and all their superclasses
+func (v Vocabulary) TypesOf(ctx context.Context, store *cayley.Handle, node quad.Value) ([]quad.Value, error) {
+	direct, err := cayley.StartPath(store, node).Out(v.Type).Iterate(ctx).AllValues(nil)
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := closure(ctx, store, direct, v.SubClassOf, true)
+	if err != nil {
+		return nil, err
+	}
+	return sorted(union(direct, inherited)), nil
+}
+
+// InstancesOf returns the nodes typed with the class or one of its subclasses. With the
+// IsA vocabulary the subclasses themselves are instances too.
+func (v Vocabulary) InstancesOf(ctx context.Context, store *cayley.Handle, class quad.Value) ([]quad.Value, error) {
+	subclasses, err := v.Subclasses(ctx, store, class)
+	if err != nil {
+		return nil, err
+	}
+	classes := append([]quad.Value{class}, subclasses...)
+
+	instances, err := cayley.StartPath(store, classes...).In(v.Type).Iterate(ctx).AllValues(nil)
+	if err != nil {
+		return nil, err
+	}
+	return sorted(union(instances)), nil
+}
+
+// Materialize writes node -Type-> class for every class a typed node inherits but is not
+// stated to have, under Label. The quads are only added: after removing type or subclass
+// edges the inferred label has to be cleared before materializing again.
+func (v Vocabulary) Materialize(ctx context.Context, store *cayley.Handle) (int, error) {
+	direct := make(map[quad.Value][]quad.Value)
+	err := cayley.StartPath(store).Tag("node").Out(v.Type).Tag("class").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		direct[m["node"]] = append(direct[m["node"]], m["class"])
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var quads []quad.Quad
+	for node, classes := range direct {
+		inherited, err := closure(ctx, store, classes, v.SubClassOf, true)
+		if err != nil {
+			return 0, err
+		}
+		stated := make(map[quad.Value]bool)
+		for _, c := range classes {
+			stated[c] = true
+		}
+		for _, c := range inherited {
+			if !stated[c] && c != node {
+				quads = append(quads, quad.Make(node, v.Type, c, Label))
+			}
+		}
+	}
+
+	w := graph.NewWriter(store)
+	if _, err := w.WriteQuads(quads); err != nil {
+		w.Close()
+		return 0, err
+	}
+	return len(quads), w.Close()
+}
+
+// closure follows the predicate from the start nodes, up (out) or down (in), until no new
+// nodes are found. The start nodes are only in the result when a cycle leads back to them.
+func closure(ctx context.Context, store *cayley.Handle, start []quad.Value, pred quad.Value, up bool) ([]quad.Value, error) {
+	seen := make(map[quad.Value]bool)
+	var found []quad.Value
+	frontier := start
+	for len(frontier) > 0 {
+		p := cayley.StartPath(store, frontier...)
+		if up {
+			p = p.Out(pred)
+		} else {
+			p = p.In(pred)
+		}
+
+		frontier = nil
+		err := p.Iterate(ctx).EachValue(nil, func(v quad.Value) {
+			if !seen[v] {
+				seen[v] = true
+				found = append(found, v)
+				frontier = append(frontier, v)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sorted(found), nil
+}
+
+// union returns the distinct values of the lists
+func union(lists ...[]quad.Value) []quad.Value {
+	seen := make(map[quad.Value]bool)
+	var values []quad.Value
+	for _, list := range lists {
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// sorted sorts values by their string form, so results do not depend on the store
+func sorted(values []quad.Value) []quad.Value {
+	sort.Slice(values, func(i, j int) bool { return values[i].String() < values[j].String() })
+	return values
+}
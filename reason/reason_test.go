@@ -0,0 +1,237 @@
+package reason
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// catalogGraph returns a memory store with a small class hierarchy in the RDFS vocabulary,
+// and a and b as subclasses of each other
+func catalogGraph(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := RDFS.SubClassOf
+	err = store.AddQuadSet([]quad.Quad{
+		quad.Make(quad.IRI("product"), sub, quad.IRI("catalog_entry"), "catalog"),
+		quad.Make(quad.IRI("product_group"), sub, quad.IRI("catalog_entry"), "catalog"),
+		quad.Make(quad.IRI("catalog_entry"), sub, quad.IRI("thing"), "catalog"),
+		quad.Make(quad.IRI("walkman"), vocab.Type, quad.IRI("product"), "catalog"),
+		quad.Make(quad.IRI("monitor"), vocab.Type, quad.IRI("product"), "catalog"),
+		quad.Make(quad.IRI("electronics"), vocab.Type, quad.IRI("product_group"), "catalog"),
+		quad.Make(quad.IRI("a"), sub, quad.IRI("b"), "catalog"),
+		quad.Make(quad.IRI("b"), sub, quad.IRI("a"), "catalog"),
+		quad.Make(quad.IRI("x"), vocab.Type, quad.IRI("a"), "catalog"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func iris(ids ...string) []quad.Value {
+	values := make([]quad.Value, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, quad.IRI(id))
+	}
+	return values
+}
+
+func TestClosure(t *testing.T) {
+	store := catalogGraph(t)
+
+	tests := []struct {
+		name  string
+		query func(context.Context, *cayley.Handle, quad.Value) ([]quad.Value, error)
+		class string
+		want  []quad.Value
+	}{
+		{"superclasses", RDFS.Superclasses, "product", iris("catalog_entry", "thing")},
+		{"no superclasses", RDFS.Superclasses, "thing", iris()},
+		{"subclasses", RDFS.Subclasses, "thing", iris("catalog_entry", "product", "product_group")},
+		{"no subclasses", RDFS.Subclasses, "product", iris()},
+		// the cycle leads back to the class itself, and ends there
+		{"cycle up", RDFS.Superclasses, "a", iris("a", "b")},
+		{"cycle down", RDFS.Subclasses, "b", iris("a", "b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query(context.TODO(), store, quad.IRI(tt.class))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) == 0 {
+				got = iris()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("classes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypesOf(t *testing.T) {
+	store := catalogGraph(t)
+
+	tests := []struct {
+		node string
+		want []quad.Value
+	}{
+		{"walkman", iris("catalog_entry", "product", "thing")},
+		{"electronics", iris("catalog_entry", "product_group", "thing")},
+		{"x", iris("a", "b")},
+		{"product", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			got, err := RDFS.TypesOf(context.TODO(), store, quad.IRI(tt.node))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("types %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstancesOf(t *testing.T) {
+	store := catalogGraph(t)
+
+	tests := []struct {
+		class string
+		want  []quad.Value
+	}{
+		{"product", iris("monitor", "walkman")},
+		{"catalog_entry", iris("electronics", "monitor", "walkman")},
+		{"thing", iris("electronics", "monitor", "walkman")},
+		{"b", iris("x")},
+		{"nothing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			got, err := RDFS.InstancesOf(context.TODO(), store, quad.IRI(tt.class))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("instances %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstancesOfIsA(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddQuadSet([]quad.Quad{
+		quad.MakeRaw("robertmeta", "is_a", "cayley advocate", "demo graph"),
+		quad.MakeRaw("cayley advocate", "is_a", "hard job without docs", "demo graph"),
+		quad.MakeRaw("barakmich", "is_a", "hard job without docs", "demo graph"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with is_a for both, the subclass is an instance too
+	got, err := IsA.InstancesOf(context.TODO(), store, quad.Raw("hard job without docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []quad.Value{quad.Raw("barakmich"), quad.Raw("cayley advocate"), quad.Raw("robertmeta")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("instances %v, want %v", got, want)
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	store := catalogGraph(t)
+
+	n, err := RDFS.Materialize(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"electronics type catalog_entry", "electronics type thing",
+		"monitor type catalog_entry", "monitor type thing",
+		"walkman type catalog_entry", "walkman type thing",
+		"x type b",
+	}
+	if n != len(want) {
+		t.Errorf("materialized %d quads, want %d", n, len(want))
+	}
+	if got := inferred(t, store); !reflect.DeepEqual(got, want) {
+		t.Errorf("inferred quads %v, want %v", got, want)
+	}
+
+	// plain queries see the inferred types
+	types, err := cayley.StartPath(store, quad.IRI("walkman")).Out(vocab.Type).Iterate(context.TODO()).AllValues(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 3 {
+		t.Errorf("walkman has types %v, want 3", types)
+	}
+
+	// the inferred quads are stated now, so nothing is left to write
+	n, err = RDFS.Materialize(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("materialized %d quads again, want 0", n)
+	}
+	if got := inferred(t, store); !reflect.DeepEqual(got, want) {
+		t.Errorf("inferred quads after a second run %v, want %v", got, want)
+	}
+}
+
+func TestMaterializeCycle(t *testing.T) {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddQuadSet([]quad.Quad{
+		quad.MakeRaw("a", "is_a", "b", "demo graph"),
+		quad.MakeRaw("b", "is_a", "a", "demo graph"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a and b already have each other as type, and are not made instances of themselves
+	n, err := IsA.Materialize(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("materialized %d quads, want 0: %v", n, inferred(t, store))
+	}
+}
+
+// inferred returns the quads under Label as sorted "subject predicate object" strings
+func inferred(t *testing.T, store *cayley.Handle) []string {
+	var quads []string
+	it := store.QuadsAllIterator()
+	defer it.Close()
+	for it.Next(context.TODO()) {
+		q := store.Quad(it.Result())
+		if quad.ToString(q.Label) == Label {
+			quads = append(quads, vocab.String(q.Subject)+" "+vocab.String(q.Predicate)+" "+vocab.String(q.Object))
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(quads)
+	return quads
+}
@@ -0,0 +1,71 @@
+package recommend
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// unavailable returns the ids of the recommended products that can not be ordered now: not
+// available or out of their tracked stock. Discontinued products are in gone, they will not
+// come back. Products without inventory data are available.
+func unavailable(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations) (soon, gone map[string]struct{}, err error) {
+	soon, gone = make(map[string]struct{}), make(map[string]struct{})
+	if len(recommendations) == 0 {
+		return soon, gone, nil
+	}
+
+	ids := make([]quad.Value, 0, len(recommendations))
+	for _, r := range recommendations {
+		ids = append(ids, quad.IRI(r.ProductID))
+	}
+
+	p := cayley.StartPath(store, ids...).Tag("product").OutWithTags([]string{"predicate"}, vocab.Stock, vocab.Available, vocab.Discontinued).Tag("value")
+	err = p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		switch m["predicate"] {
+		case vocab.Stock:
+			if stock, ok := toFloat(m["value"]); ok && stock <= 0 {
+				soon[id] = struct{}{}
+			}
+		case vocab.Available:
+			if available, ok := m["value"].(quad.Bool); ok && !bool(available) {
+				soon[id] = struct{}{}
+			}
+		case vocab.Discontinued:
+			if discontinued, ok := m["value"].(quad.Bool); ok && bool(discontinued) {
+				gone[id] = struct{}{}
+			}
+		}
+	})
+	for id := range gone {
+		delete(soon, id)
+	}
+	return soon, gone, err
+}
+
+// onlyAvailable drops discontinued products and, unless Options.ShowUnavailable is set,
+// the ones that are not available now. Shown unavailable products are marked BackInStockSoon.
+func onlyAvailable(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, opts Options) (ProductRecommendations, error) {
+	soon, gone, err := unavailable(ctx, store, recommendations)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := recommendations[:0]
+	for _, r := range recommendations {
+		if _, ok := gone[r.ProductID]; ok {
+			continue
+		}
+		if _, ok := soon[r.ProductID]; ok {
+			if !opts.ShowUnavailable {
+				continue
+			}
+			r.BackInStockSoon = true
+		}
+		kept = append(kept, r)
+	}
+	return kept, nil
+}
@@ -0,0 +1,82 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// BasketItem is a product in a basket. Its co-purchase evidence counts Weight times,
+// a zero weight counts as 1 and a negative weight is an error.
+type BasketItem struct {
+	ProductID string  `json:"product_id"`
+	Weight    float64 `json:"weight"`
+}
+
+// ForBasket recommends products for a basket of several products. It scores the
+// co-purchases of every item like ForProduct and adds up the scores, weighted per item.
+// The count of a recommendation is the number of customers behind it, a customer who
+// bought several items of the basket counts once. Products in the basket are never recommended.
+func ForBasket(ctx context.Context, store *cayley.Handle, basket []BasketItem, opts Options) (ProductRecommendations, error) {
+	if len(basket) == 0 {
+		return nil, errors.New("empty basket")
+	}
+
+	excluded := blocklist(opts)
+	products := make([]quad.Value, 0, len(basket))
+	for _, item := range basket {
+		if item.Weight < 0 {
+			return nil, fmt.Errorf("negative weight %v for %s", item.Weight, item.ProductID)
+		}
+		excluded[item.ProductID] = struct{}{}
+		products = append(products, quad.IRI(item.ProductID))
+	}
+
+	merged := make(map[string]*candidate)
+	for _, item := range basket {
+		weight := item.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		p, s := productPath(store, quad.IRI(item.ProductID), opts)
+		candidates, err := scored(ctx, store, p, s, excluded, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for id, c := range candidates {
+			m, ok := merged[id]
+			if !ok {
+				m = &candidate{
+					product:   c.product,
+					rec:       c.rec,
+					customers: make(map[string]string),
+				}
+				m.rec.Count, m.rec.Score = 0, 0
+				merged[id] = m
+			}
+			m.rec.Score += weight * c.rec.Score
+			m.weight += c.weight
+			for customer, name := range c.customers {
+				m.customers[customer] = name
+			}
+		}
+	}
+	for _, m := range merged {
+		m.rec.Count = int32(len(m.customers))
+	}
+
+	basketPath := cayley.StartPath(store, products...)
+	// the basket is not bought yet, so the explanations do not call it "your"
+	s := seed{products: basketPath, buyers: basketPath.FollowReverse(bought).Unique()}
+
+	recommendations, err := finish(ctx, store, merged, s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withFallback(ctx, store, recommendations, basketPath, excluded, opts)
+}
@@ -0,0 +1,45 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestForBasket(t *testing.T) {
+	store := seededStore(t)
+	basket := []BasketItem{{ProductID: walkman}, {ProductID: pencil, Weight: 2}}
+
+	recs, err := ForBasket(context.TODO(), store, basket, Options{NoFallback: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(recs), []string{harddrive, monitor}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("recommended %v, want %v", got, want)
+	}
+
+	// Casper bought both items and counts once for the harddrive
+	harddriveRec := recs[0]
+	if harddriveRec.Count != 1 {
+		t.Errorf("harddrive has count %d, want 1", harddriveRec.Count)
+	}
+	if want := "bought by Casper, who also bought Pencil and Walkman"; harddriveRec.Explanation == nil || harddriveRec.Explanation.Text != want {
+		t.Errorf("harddrive explained as %+v, want %q", harddriveRec.Explanation, want)
+	}
+}
+
+func TestForBasketInvalid(t *testing.T) {
+	store := seededStore(t)
+	tests := []struct {
+		name   string
+		basket []BasketItem
+	}{
+		{"empty", nil},
+		{"negative weight", []BasketItem{{ProductID: walkman}, {ProductID: pencil, Weight: -1}}},
+	}
+	for _, tt := range tests {
+		if _, err := ForBasket(context.TODO(), store, tt.basket, Options{}); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Exclusion is the policy that decides which of a customer's purchases are kept
+// out of their recommendations
+type Exclusion string
+
+const (
+	// ExcludePurchased excludes everything the customer ever bought
+	ExcludePurchased Exclusion = "purchased"
+	// ExcludeRecentConsumables only excludes consumables the customer bought within
+	// Options.ConsumableWindow before Options.Now. Purchases without a known time count as recent.
+	ExcludeRecentConsumables Exclusion = "recent_consumables"
+	// ExcludeNone excludes no purchases, only the products in Options.Blocklist
+	ExcludeNone Exclusion = "none"
+)
+
+// DefaultConsumableWindow is used by ExcludeRecentConsumables when Options.ConsumableWindow is not set
+const DefaultConsumableWindow = 30 * 24 * time.Hour
+
+// ParseExclusion returns the exclusion policy with the given name, an empty name gives ExcludePurchased
+func ParseExclusion(name string) (Exclusion, error) {
+	switch Exclusion(name) {
+	case "":
+		return ExcludePurchased, nil
+	case ExcludePurchased, ExcludeRecentConsumables, ExcludeNone:
+		return Exclusion(name), nil
+	}
+	return "", fmt.Errorf("unknown exclusion policy %q", name)
+}
+
+// excludedFor returns the ids of the products that must not be recommended to the customer
+func excludedFor(ctx context.Context, store *cayley.Handle, customer quad.Value, opts Options) (map[string]struct{}, error) {
+	excluded := blocklist(opts)
+
+	switch opts.Exclusion {
+	case "", ExcludePurchased:
+		purchases, err := purchasesOf(ctx, store, customer)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range purchases {
+			excluded[p.product] = struct{}{}
+		}
+
+	case ExcludeRecentConsumables:
+		purchases, err := purchasesOf(ctx, store, customer)
+		if err != nil {
+			return nil, err
+		}
+
+		consumables, err := valueSet(ctx, cayley.StartPath(store, customer).Follow(bought).Has(vocab.Consumable, quad.Bool(true)))
+		if err != nil {
+			return nil, err
+		}
+
+		window := opts.ConsumableWindow
+		if window <= 0 {
+			window = DefaultConsumableWindow
+		}
+		now := opts.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		since := now.Add(-window)
+
+		for _, p := range purchases {
+			if _, ok := consumables[p.product]; !ok {
+				continue
+			}
+			if p.at.IsZero() || p.at.After(since) {
+				excluded[p.product] = struct{}{}
+			}
+		}
+
+	case ExcludeNone:
+
+	default:
+		return nil, fmt.Errorf("unknown exclusion policy %q", opts.Exclusion)
+	}
+
+	return excluded, nil
+}
+
+// blocklist returns the product ids of Options.Blocklist as a set
+func blocklist(opts Options) map[string]struct{} {
+	blocked := make(map[string]struct{}, len(opts.Blocklist))
+	for _, id := range opts.Blocklist {
+		blocked[id] = struct{}{}
+	}
+	return blocked
+}
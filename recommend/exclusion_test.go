@@ -0,0 +1,155 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/model"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+const (
+	john   = "3117979d-516a-4bac-a55e-b71g4dcb2351"
+	alice  = "3217979d-516a-4bac-a55e-b71f4dcb2352"
+	casper = "3417979d-516a-4bac-a55e-b71d4dcb2355"
+
+	walkman   = "2017979d-516a-4bac-a55e-b71c4dcb2351"
+	pencil    = "2017979d-516a-4bac-a55e-b71c4dcb2354"
+	pen       = "2017979d-516a-4bac-a55e-b71c4dcb2355"
+	monitor   = "2017979d-516a-4bac-a55e-b71c4dcb2360"
+	harddrive = "2017979d-516a-4bac-a55e-b71c4dcb2365"
+)
+
+// seededStore returns a memory store with John, Alice and Casper and their purchases
+// as seeded by cmd/recommendations
+func seededStore(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := graph.NewWriter(store)
+	clients := []model.Client{
+		{ID: quad.IRI(john), Firstname: "John", Lastname: "Doe"},
+		{ID: quad.IRI(alice), Firstname: "Alice", Lastname: "Blue"},
+		{ID: quad.IRI(casper), Firstname: "Casper", Lastname: "Walden"},
+	}
+	for _, c := range clients {
+		if err := model.SaveClient(w, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	products := []model.Product{
+		{ID: quad.IRI(walkman), Label: "Walkman", Desc: "This is a description", Price: 12.3, Group: "electronics"},
+		{ID: quad.IRI(pencil), Label: "Pencil", Desc: "This is a description", Price: 76.3, Group: "utensils", Consumable: true},
+		{ID: quad.IRI(pen), Label: "Pen", Desc: "This is a description", Price: 2.3, Group: "utensils", Consumable: true},
+		{ID: quad.IRI(monitor), Label: "Monitor", Desc: "This is a description", Price: 321.3, Group: "electronics"},
+		{ID: quad.IRI(harddrive), Label: "Harddrive", Desc: "This is a description", Price: 202.3, Group: "electronics"},
+	}
+	prices := make(map[quad.IRI]float64)
+	for _, p := range products {
+		if err := model.SaveProduct(w, p); err != nil {
+			t.Fatal(err)
+		}
+		prices[p.ID] = p.Price
+	}
+	purchases := []struct {
+		client, product string
+		at              time.Time
+	}{
+		{john, walkman, date(2017, 1, 10)},
+		{john, monitor, date(2017, 1, 10)},
+		{alice, pencil, date(2017, 2, 3)},
+		{alice, walkman, date(2017, 2, 3)},
+		{casper, harddrive, date(2017, 3, 14)},
+		{casper, pencil, date(2017, 3, 14)},
+		{casper, walkman, date(2017, 3, 14)},
+	}
+	for _, p := range purchases {
+		purchase := model.Purchase{Client: quad.IRI(p.client), Product: quad.IRI(p.product), At: p.at, Quantity: 1, UnitPrice: prices[quad.IRI(p.product)]}
+		err := model.SavePurchase(w, purchase)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// addUndated adds a purchase of a product by a client without a time
+func addUndated(t *testing.T, store *cayley.Handle, client, product string) {
+	purchase := quad.IRI("undated-" + client + "-" + product)
+	err := store.AddQuadSet([]quad.Quad{
+		quad.Make(quad.IRI(client), vocab.HasPurchase, purchase, nil),
+		quad.Make(purchase, vocab.PurchaseOf, quad.IRI(product), nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestExcludedFor(t *testing.T) {
+	store := seededStore(t)
+	// a pen bought at an unknown time counts as a recent purchase
+	addUndated(t, store, john, pen)
+	// a week after Casper's purchases, six weeks after Alice's
+	now := date(2017, 3, 21)
+
+	tests := []struct {
+		name     string
+		customer string
+		opts     Options
+		want     []string
+	}{
+		{"john purchased", john, Options{}, []string{walkman, monitor, pen}},
+		{"alice purchased", alice, Options{Exclusion: ExcludePurchased}, []string{walkman, pencil}},
+		{"casper purchased", casper, Options{Exclusion: ExcludePurchased}, []string{walkman, pencil, harddrive}},
+
+		{"john bought his pen at an unknown time", john, Options{Exclusion: ExcludeRecentConsumables, Now: now}, []string{pen}},
+		{"alice bought her pencil too long ago", alice, Options{Exclusion: ExcludeRecentConsumables, Now: now}, nil},
+		{"casper bought his pencil recently", casper, Options{Exclusion: ExcludeRecentConsumables, Now: now}, []string{pencil}},
+		{"alice within a longer window", alice, Options{Exclusion: ExcludeRecentConsumables, Now: now, ConsumableWindow: 60 * 24 * time.Hour}, []string{pencil}},
+
+		{"casper none", casper, Options{Exclusion: ExcludeNone}, nil},
+		{"john none with a blocklist", john, Options{Exclusion: ExcludeNone, Blocklist: []string{pen, monitor}}, []string{pen, monitor}},
+		{"john purchased with a blocklist", john, Options{Blocklist: []string{pencil}}, []string{walkman, monitor, pen, pencil}},
+		{"casper recent consumables with a blocklist", casper, Options{Exclusion: ExcludeRecentConsumables, Now: now, Blocklist: []string{pen}}, []string{pencil, pen}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excluded, err := excludedFor(context.TODO(), store, quad.IRI(tt.customer), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for id := range excluded {
+				got = append(got, id)
+			}
+			want := append([]string{}, tt.want...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("excluded %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExcludedForUnknownPolicy(t *testing.T) {
+	store := seededStore(t)
+	if _, err := excludedFor(context.TODO(), store, quad.IRI(john), Options{Exclusion: "everything"}); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,173 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// maxExplainedCustomers is the number of supporting customers kept in an explanation
+const maxExplainedCustomers = 10
+
+// Explanation tells why a product is recommended, with the ids of the customers and
+// products that support it
+type Explanation struct {
+	Text string `json:"text"`
+	// Customers are the customers who bought the recommended product
+	Customers []string `json:"customers,omitempty"`
+	// Products are the seed products the recommendation is related to
+	Products []string `json:"products,omitempty"`
+	// Group is the product group shared with the seed products
+	Group string `json:"group,omitempty"`
+}
+
+// named is an id with its label
+type named struct {
+	id, name string
+}
+
+// explain attaches an explanation to each co-purchase recommendation, like
+// "bought by Alice and Casper, who also bought your Walkman" or "in Electronics like your Monitor"
+func explain(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, candidates map[string]*candidate, s seed) error {
+	your := ""
+	if s.own {
+		your = "your "
+	}
+
+	for i := range recommendations {
+		r := &recommendations[i]
+		c, ok := candidates[r.ProductID]
+		if !ok {
+			continue
+		}
+
+		var customers []named
+		for id, name := range c.customers {
+			customers = append(customers, named{id: id, name: name})
+		}
+		sort.Slice(customers, func(i, j int) bool {
+			if customers[i].name != customers[j].name {
+				return customers[i].name < customers[j].name
+			}
+			return customers[i].id < customers[j].id
+		})
+		supporting := customers
+		if len(supporting) > maxExplainedCustomers {
+			supporting = supporting[:maxExplainedCustomers]
+		}
+
+		e := &Explanation{}
+		var ids []quad.Value
+		for _, cu := range supporting {
+			e.Customers = append(e.Customers, cu.id)
+			ids = append(ids, quad.IRI(cu.id))
+		}
+
+		// which of the seed products did the supporting customers buy?
+		var shared []named
+		if len(ids) > 0 {
+			var err error
+			shared, err = names(ctx, cayley.StartPath(store, ids...).Follow(bought).And(s.products))
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case len(shared) > 0:
+			e.Text = fmt.Sprintf("bought by %s, who also bought %s%s", join(customers), your, join(shared))
+			for _, p := range shared {
+				e.Products = append(e.Products, p.id)
+			}
+		default:
+			// is it in the same group as one of the seed products?
+			var group, groupName string
+			var like []named
+			p := cayley.StartPath(store, c.product).Out(vocab.InGroup).Tag("group").Save(vocab.Label, "group_name").
+				In(vocab.InGroup).And(s.products).Tag("product").Save(vocab.Label, "name")
+			err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+				g := vocab.String(m["group"])
+				if group == "" {
+					group, groupName = g, vocab.String(m["group_name"])
+				}
+				if g == group {
+					like = append(like, named{id: vocab.String(m["product"]), name: vocab.String(m["name"])})
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			if group != "" {
+				sortNamed(like)
+				e.Text = fmt.Sprintf("in %s like %s%s", groupName, your, join(like))
+				e.Group = group
+				for _, l := range like {
+					e.Products = append(e.Products, l.id)
+				}
+				if len(customers) > 0 {
+					e.Text += ", bought by " + join(customers)
+				}
+			} else if len(customers) > 0 {
+				e.Text = "bought by " + join(customers)
+			}
+		}
+
+		r.Explanation = e
+	}
+	return nil
+}
+
+// names returns the distinct values of a path with their labels, sorted by label
+func names(ctx context.Context, p *path.Path) ([]named, error) {
+	seen := make(map[string]bool)
+	var list []named
+	err := p.Tag("id").Save(vocab.Label, "name").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["id"])
+		if !seen[id] {
+			seen[id] = true
+			list = append(list, named{id: id, name: vocab.String(m["name"])})
+		}
+	})
+	sortNamed(list)
+	return list, err
+}
+
+func sortNamed(list []named) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].name != list[j].name {
+			return list[i].name < list[j].name
+		}
+		return list[i].id < list[j].id
+	})
+}
+
+// join lists names like "Alice", "Alice and Casper" or "Alice, Casper and 2 others"
+func join(list []named) string {
+	const shown = 2
+	var parts []string
+	for i, n := range list {
+		if i == shown {
+			break
+		}
+		parts = append(parts, n.name)
+	}
+
+	switch {
+	case len(list) == 0:
+		return ""
+	case len(list) == 1:
+		return parts[0]
+	case len(list) == shown:
+		return parts[0] + " and " + parts[1]
+	case len(list) == shown+1:
+		return strings.Join(parts, ", ") + " and 1 other"
+	}
+	return fmt.Sprintf("%s and %d others", strings.Join(parts, ", "), len(list)-shown)
+}
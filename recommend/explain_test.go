@@ -0,0 +1,107 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+func TestExplain(t *testing.T) {
+	store := seededStore(t)
+	err := store.AddQuadSet([]quad.Quad{
+		quad.Make(quad.IRI("electronics"), vocab.Label, "Electronics", nil),
+		quad.Make(quad.IRI("utensils"), vocab.Label, "Utensils", nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customerSeed := func(customer string) seed {
+		return seed{products: cayley.StartPath(store, quad.IRI(customer)).Follow(bought), own: true}
+	}
+	productSeed := func(product string) seed {
+		return seed{products: cayley.StartPath(store, quad.IRI(product))}
+	}
+
+	tests := []struct {
+		name      string
+		product   string
+		customers map[string]string
+		seed      seed
+		want      *Explanation
+	}{
+		{
+			"own purchases", walkman, map[string]string{john: "John", alice: "Alice"}, customerSeed(casper),
+			&Explanation{
+				Text:      "bought by Alice and John, who also bought your Pencil and Walkman",
+				Customers: []string{alice, john},
+				Products:  []string{pencil, walkman},
+			},
+		},
+		{
+			"seed product", walkman, map[string]string{alice: "Alice", casper: "Casper"}, productSeed(pencil),
+			&Explanation{
+				Text:      "bought by Alice and Casper, who also bought Pencil",
+				Customers: []string{alice, casper},
+				Products:  []string{pencil},
+			},
+		},
+		{
+			"own group", pen, nil, customerSeed(alice),
+			&Explanation{Text: "in Utensils like your Pencil", Products: []string{pencil}, Group: "utensils"},
+		},
+		{
+			"group of the seed product", harddrive, map[string]string{casper: "Casper"}, productSeed(monitor),
+			&Explanation{
+				Text:      "in Electronics like Monitor, bought by Casper",
+				Customers: []string{casper},
+				Products:  []string{monitor},
+				Group:     "electronics",
+			},
+		},
+		{
+			"other group", pencil, map[string]string{alice: "Alice"}, productSeed(monitor),
+			&Explanation{Text: "bought by Alice", Customers: []string{alice}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &candidate{
+				product:   quad.IRI(tt.product),
+				rec:       ProductRecommendation{ProductID: tt.product},
+				customers: tt.customers,
+			}
+			recommendations := ProductRecommendations{c.rec}
+			candidates := map[string]*candidate{tt.product: c}
+			if err := explain(context.TODO(), store, recommendations, candidates, tt.seed); err != nil {
+				t.Fatal(err)
+			}
+			if got := recommendations[0].Explanation; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("explanation %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	people := []named{{"1", "Alice"}, {"2", "Casper"}, {"3", "John"}, {"4", "Jase"}}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		{1, "Alice"},
+		{2, "Alice and Casper"},
+		{3, "Alice, Casper and 1 other"},
+		{4, "Alice, Casper and 2 others"},
+	}
+	for _, tt := range tests {
+		if got := join(people[:tt.n]); got != tt.want {
+			t.Errorf("join of %d = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package recommend
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/als"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// StrategyALS is the strategy of the recommendations made by ModelForCustomer
+const StrategyALS Strategy = "als"
+
+// TierALS is a recommendation predicted by a matrix factorization model
+const TierALS Tier = "als"
+
+// ModelForCustomer recommends the products with the highest predicted preference in a
+// trained ALS model. It returns als.ErrStale when the purchases changed since the model was
+// trained. Exclusion, pagination and fallback work as for ForCustomer, customers the model
+// does not know only get the fallback.
+func ModelForCustomer(ctx context.Context, store *cayley.Handle, model *als.Model, customer quad.Value, opts Options) (ProductRecommendations, error) {
+	stale, err := model.Stale(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		return nil, als.ErrStale
+	}
+
+	excluded, err := excludedFor(ctx, store, customer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := model.Scores(vocab.String(customer))
+	var ids []quad.Value
+	for id := range scores {
+		if _, ok := excluded[id]; !ok {
+			ids = append(ids, quad.IRI(id))
+		}
+	}
+
+	var recommendations ProductRecommendations
+	if len(ids) > 0 {
+		recommendations, err = withLabels(ctx, cayley.StartPath(store, ids...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range recommendations {
+		r := &recommendations[i]
+		r.Score = scores[r.ProductID]
+		r.Strategy = StrategyALS
+		r.Tier = TierALS
+		r.Explanation = &Explanation{Text: "bought by customers with similar purchases"}
+	}
+
+	products := cayley.StartPath(store, customer).Follow(bought)
+	recommendations, err = selectPage(ctx, store, recommendations, products, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withFallback(ctx, store, recommendations, products, excluded, opts)
+}
@@ -0,0 +1,203 @@
+package recommend
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Tier tells where a recommendation came from
+type Tier string
+
+const (
+	// TierCoPurchase is a recommendation found through co-purchases
+	TierCoPurchase Tier = "co_purchase"
+	// TierGroupBestseller is a bestseller from the groups of the seed products
+	TierGroupBestseller Tier = "group_bestseller"
+	// TierBestseller is a bestseller from the whole catalog
+	TierBestseller Tier = "bestseller"
+	// TierNewest is one of the most recently added products
+	TierNewest Tier = "newest"
+)
+
+// DefaultFallbackSize is the number of fallback recommendations returned when there
+// are no co-purchase recommendations and Options.Limit is not set
+const DefaultFallbackSize = 10
+
+// withFallback fills up the first page of recommendations with the bestsellers of the
+// groups of the seed products, then the global bestsellers and then the newest products.
+// Later pages are left as they are: where the fallback of the first page ended is not known
+// without ranking it again.
+// Fallback recommendations have a score of 0, so they rank below the ones of the strategies,
+// and the bestsellers carry their number of purchases as count. They go through the same
+// availability, rules and minimum filters as the recommendations, and pinned and buried
+// products are moved again once the page is filled.
+func withFallback(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, seedProducts *path.Path, excluded map[string]struct{}, opts Options) (ProductRecommendations, error) {
+	offset, want := bounds(opts)
+	if opts.NoFallback || offset > 0 {
+		return recommendations, nil
+	}
+
+	if want == 0 {
+		if len(recommendations) > 0 {
+			return recommendations, nil
+		}
+		want = DefaultFallbackSize
+	}
+
+	seen := make(map[string]struct{}, len(excluded)+len(recommendations))
+	for id := range excluded {
+		seen[id] = struct{}{}
+	}
+	for _, r := range recommendations {
+		seen[r.ProductID] = struct{}{}
+	}
+
+	groups := seedProducts.Out(vocab.InGroup).Unique()
+	products := cayley.StartPath(store, vocab.ClassProduct).In(vocab.Type)
+	tiers := []struct {
+		tier Tier
+		why  string
+		list func() (ProductRecommendations, error)
+	}{
+		{TierGroupBestseller, "one of the bestsellers in its group", func() (ProductRecommendations, error) {
+			return bestsellers(ctx, groups.In(vocab.InGroup).And(products))
+		}},
+		{TierBestseller, "one of our bestsellers", func() (ProductRecommendations, error) {
+			return bestsellers(ctx, products)
+		}},
+		{TierNewest, "new in the catalog", func() (ProductRecommendations, error) {
+			return newest(ctx, products)
+		}},
+	}
+
+	filled := false
+	for _, t := range tiers {
+		if len(recommendations) >= want {
+			break
+		}
+
+		list, err := t.list()
+		if err != nil {
+			return nil, err
+		}
+		list, _, err = applyRules(ctx, store, list, seedProducts, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			if len(recommendations) >= want {
+				break
+			}
+			if _, ok := seen[r.ProductID]; ok {
+				continue
+			}
+			seen[r.ProductID] = struct{}{}
+			r.Tier = t.tier
+			r.Explanation = &Explanation{Text: t.why}
+			recommendations = append(recommendations, r)
+			filled = true
+		}
+	}
+
+	if rules := opts.Rules; filled && rules != nil {
+		attrs, err := attributesOf(ctx, store, recommendations)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = moveIDs(recommendations, attrs, rules.Bury, false)
+		recommendations = moveIDs(recommendations, attrs, rules.Pin, true)
+	}
+	return recommendations, nil
+}
+
+// bestsellers returns the products that were purchased, ordered by the number of times
+func bestsellers(ctx context.Context, products *path.Path) (ProductRecommendations, error) {
+	all, err := withLabels(ctx, products.Unique())
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := countPurchases(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	var list ProductRecommendations
+	for _, r := range all {
+		count := counts[r.ProductID]
+		if count == 0 {
+			continue
+		}
+		r.Count = int32(count)
+		list = append(list, r)
+	}
+	sort.Sort(list)
+	return list, nil
+}
+
+// countPurchases counts the purchases of the products, their in-degree over "purchase_of"
+// edges, in one pass over the edges. Products that were never purchased are left out.
+func countPurchases(ctx context.Context, products *path.Path) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	// a product can be reached more than once, the Unique iterator would drop the tag
+	seen := make(map[[2]string]struct{})
+	p := products.Tag("product").In(vocab.PurchaseOf).Tag("purchase")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		key := [2]string{vocab.String(m["product"]), vocab.String(m["purchase"])}
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		counts[key[0]]++
+	})
+	return counts, err
+}
+
+// newest returns the products with an "added" time, most recently added first
+func newest(ctx context.Context, products *path.Path) (ProductRecommendations, error) {
+	var list ProductRecommendations
+	added := make(map[string]time.Time)
+
+	p := products.Unique().Tag("product").Save(vocab.Label, "name").Save(vocab.Added, "added")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		at, ok := m["added"].(quad.Time)
+		if !ok {
+			return
+		}
+		id := vocab.String(m["product"])
+		if _, ok := added[id]; !ok {
+			list = append(list, ProductRecommendation{ProductID: id, Name: vocab.String(m["name"])})
+		}
+		added[id] = time.Time(at)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := added[list[i].ProductID], added[list[j].ProductID]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return list[i].ProductID < list[j].ProductID
+	})
+	return list, nil
+}
+
+// withLabels returns the products of a path with their labels
+func withLabels(ctx context.Context, products *path.Path) (ProductRecommendations, error) {
+	var list ProductRecommendations
+	err := products.Tag("product").Save(vocab.Label, "name").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		list = append(list, ProductRecommendation{
+			ProductID: vocab.String(m["product"]),
+			Name:      vocab.String(m["name"]),
+		})
+	})
+	return list, err
+}
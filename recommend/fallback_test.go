@@ -0,0 +1,73 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func TestWithFallback(t *testing.T) {
+	store := seededStore(t)
+	excluded := map[string]struct{}{walkman: {}, monitor: {}}
+
+	harddriveInGroup := ProductRecommendation{ProductID: harddrive, Name: "Harddrive", Count: 1, Tier: TierGroupBestseller}
+	pencilGlobal := ProductRecommendation{ProductID: pencil, Name: "Pencil", Count: 2, Tier: TierBestseller}
+	copurchase := ProductRecommendation{ProductID: pen, Name: "Pen", Count: 1, Score: 0.5, Tier: TierCoPurchase}
+
+	tests := []struct {
+		name            string
+		recommendations ProductRecommendations
+		opts            Options
+		want            ProductRecommendations
+	}{
+		// the pen was never bought and none of the products has an added time
+		{"tiers", nil, Options{Limit: 10}, ProductRecommendations{harddriveInGroup, pencilGlobal}},
+		{"filled up", ProductRecommendations{copurchase}, Options{Limit: 2}, ProductRecommendations{copurchase, harddriveInGroup}},
+		{"no fallback", nil, Options{Limit: 10, NoFallback: true}, nil},
+		// only the first page is filled up
+		{"later page", nil, Options{Limit: 10, Offset: 10}, nil},
+		{"min count", nil, Options{Limit: 10, MinCount: 2}, ProductRecommendations{pencilGlobal}},
+		{"min score", nil, Options{Limit: 10, MinScore: 0.1}, nil},
+		// half the price of the monitor
+		{"price band", nil, Options{Limit: 10, Rules: &Rules{PriceBand: &PriceBand{Max: 0.5}}}, ProductRecommendations{pencilGlobal}},
+		{"pin", ProductRecommendations{copurchase}, Options{Limit: 10, Rules: &Rules{Pin: []string{pencil}}}, ProductRecommendations{pencilGlobal, copurchase, harddriveInGroup}},
+		{"bury", ProductRecommendations{copurchase}, Options{Limit: 10, Rules: &Rules{Bury: []string{"utensils"}}}, ProductRecommendations{harddriveInGroup, copurchase, pencilGlobal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seedProducts := cayley.StartPath(store, quad.IRI(monitor))
+			recommendations := append(ProductRecommendations(nil), tt.recommendations...)
+
+			got, err := withFallback(context.TODO(), store, recommendations, seedProducts, excluded, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i].Explanation = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fallback %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountPurchases(t *testing.T) {
+	store := seededStore(t)
+	// a second purchase of the harddrive by Casper counts too
+	addUndated(t, store, casper, harddrive)
+
+	// the walkman is reached twice
+	products := cayley.StartPath(store, quad.IRI(walkman), quad.IRI(harddrive), quad.IRI(pen)).Or(cayley.StartPath(store, quad.IRI(walkman)))
+	got, err := countPurchases(context.TODO(), products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{walkman: 3, harddrive: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counts %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package recommend
+
+import (
+	"context"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// bought follows a client to the products they bought, use FollowReverse to go
+// from products to the clients that bought them
+var bought = path.StartMorphism().Out(vocab.HasPurchase).Out(vocab.PurchaseOf)
+
+// purchase is a product bought by a customer, at is zero when the time is unknown
+type purchase struct {
+	product string
+	at      time.Time
+}
+
+// purchasesOf returns the purchases of a customer
+func purchasesOf(ctx context.Context, store *cayley.Handle, customer quad.Value) ([]purchase, error) {
+	var purchases []purchase
+	p := cayley.StartPath(store, customer).Out(vocab.HasPurchase).SaveOptional(vocab.At, "at").Out(vocab.PurchaseOf).Tag("product")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		pu := purchase{product: vocab.String(m["product"])}
+		if at, ok := m["at"].(quad.Time); ok {
+			pu.at = time.Time(at)
+		}
+		purchases = append(purchases, pu)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purchases, nil
+}
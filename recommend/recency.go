@@ -0,0 +1,59 @@
+package recommend
+
+import (
+	"math"
+	"time"
+
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// boughtWithin follows customers to the products they bought within Options.Since
+// and Options.Until, and tags the time of the purchase as "at"
+func boughtWithin(p *path.Path, opts Options) *path.Path {
+	return within(p.Out(vocab.HasPurchase), opts).Out(vocab.PurchaseOf)
+}
+
+// boughtByWithin follows products to the customers that bought them within Options.Since
+// and Options.Until, and tags the time of the purchase as "at"
+func boughtByWithin(p *path.Path, opts Options) *path.Path {
+	return within(p.In(vocab.PurchaseOf), opts).In(vocab.HasPurchase)
+}
+
+// within restricts a path of purchase nodes to the time window of the options. Purchases
+// without a time are only kept when there is no window, and are tagged without "at".
+func within(p *path.Path, opts Options) *path.Path {
+	p = p.Tag("purchase")
+	if !opts.Since.IsZero() {
+		p = p.Out(vocab.At).Filter(iterator.CompareGTE, quad.Time(opts.Since)).Back("purchase")
+	}
+	if !opts.Until.IsZero() {
+		p = p.Out(vocab.At).Filter(iterator.CompareLT, quad.Time(opts.Until)).Back("purchase")
+	}
+	return p.SaveOptional(vocab.At, "at")
+}
+
+// recencyWeight returns the weight of a purchase made at the given time: 1 for a
+// purchase made now, halving every Options.HalfLife. Without a half-life, or a time, a
+// purchase weighs 1.
+func recencyWeight(at quad.Value, opts Options) float64 {
+	if opts.HalfLife <= 0 {
+		return 1
+	}
+	t, ok := at.(quad.Time)
+	if !ok {
+		return 1
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	age := now.Sub(time.Time(t))
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(opts.HalfLife))
+}
@@ -0,0 +1,344 @@
+// Package recommend finds product recommendations in a cayley graph of
+// clients, products and product groups.
+package recommend
+
+import (
+	"context"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+type Product struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+}
+
+type ProductRecommendation struct {
+	ProductID string   `json:"product_id"`
+	Name      string   `json:"name"`
+	Count     int32    `json:"count"`
+	Score     float64  `json:"score"`
+	Strategy  Strategy `json:"strategy,omitempty"`
+	Tier      Tier     `json:"tier"`
+	// BackInStockSoon marks an unavailable product, shown with Options.ShowUnavailable
+	BackInStockSoon bool `json:"back_in_stock_soon,omitempty"`
+	// Explanation tells why the product is recommended
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+type ProductRecommendations []ProductRecommendation
+
+func (slice ProductRecommendations) Len() int {
+	return len(slice)
+}
+
+func (slice ProductRecommendations) Less(i, j int) bool {
+	return better(slice[i], slice[j])
+}
+
+// better orders recommendations by score and count, ties are broken by name and
+// product id so the order is stable between runs
+func better(a, b ProductRecommendation) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ProductID < b.ProductID
+}
+
+func (slice ProductRecommendations) Swap(i, j int) {
+	slice[i], slice[j] = slice[j], slice[i]
+}
+
+// Options tune how recommendations are scored and selected. The zero value returns
+// all candidates ranked by raw co-purchase counts.
+type Options struct {
+	// Strategy scores the candidates, defaults to StrategyCount
+	Strategy Strategy
+	// SameGroup makes ForProduct only recommend products from the seed product's group
+	SameGroup bool
+	// TextWeight blends the text similarity of label and desc into the scores of ForProduct,
+	// from 0 (only co-purchases) to 1 (only text)
+	TextWeight float64
+	// TextIndex is the index TextWeight uses, build it once with NewTextIndex and again when
+	// the products change. Without it every call indexes the products.
+	TextIndex *TextIndex
+
+	// Limit is the maximum number of recommendations returned, 0 or less means all
+	Limit int
+	// Offset skips the first recommendations, for pagination, less than 0 counts as 0
+	Offset int
+	// MinCount drops recommendations with fewer co-purchases
+	MinCount int32
+	// MinScore drops recommendations with a lower score
+	MinScore float64
+
+	// Exclusion decides which of the customer's purchases ForCustomer leaves out,
+	// defaults to ExcludePurchased
+	Exclusion Exclusion
+	// ConsumableWindow is how long a consumable counts as recently bought for ExcludeRecentConsumables
+	ConsumableWindow time.Duration
+	// Blocklist holds product ids that are never recommended
+	Blocklist []string
+
+	// Since and Until limit the co-purchases that count to the ones made in [Since, Until),
+	// a zero time leaves that side open
+	Since, Until time.Time
+	// HalfLife makes co-purchases count for half every HalfLife before Now, 0 disables the decay
+	HalfLife time.Duration
+	// Now is the time the decay and the consumable window are calculated from, defaults to time.Now()
+	Now time.Time
+
+	// ShowUnavailable keeps products that are out of stock or not available, marked
+	// BackInStockSoon, instead of leaving them out. Discontinued products are always left out.
+	ShowUnavailable bool
+
+	// Rules re-rank the recommendations before the page is selected, see LoadRules
+	Rules *Rules
+
+	// NoFallback disables filling up short or empty results with bestsellers and new products.
+	// Only the first page is filled up, pages with an Offset hold co-purchases only.
+	NoFallback bool
+}
+
+// ProductsForCustomer returns the products the customer bought
+func ProductsForCustomer(ctx context.Context, store *cayley.Handle, customer quad.Value) ([]Product, error) {
+	p := cayley.StartPath(store, customer).Follow(bought).Tag("product").Save(vocab.Label, "name")
+
+	products := []Product{}
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		products = append(products, Product{
+			ProductID: vocab.String(m["product"]),
+			Name:      vocab.String(m["name"]),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ForCustomer recommends products from the groups the customer bought from, ranked by
+// how many other customers bought them (within the time window of the options):
+//
+//	c1 -> products1 -> group <- products2 <- c2
+//
+// Products are left out according to Options.Exclusion and Options.Blocklist. When there
+// are too few co-purchases the result is filled up with bestsellers from the customer's
+// groups, global bestsellers and the newest products.
+func ForCustomer(ctx context.Context, store *cayley.Handle, customer quad.Value, opts Options) (ProductRecommendations, error) {
+	// start from the customer
+	current_customer := cayley.StartPath(store, customer)
+
+	// find the articles the customer bought
+	customer_articles := current_customer.Follow(bought) //.Unique() // this one makes it a bit slower
+	product_groups := customer_articles.Out(vocab.InGroup).Unique()
+
+	// who else bought these articles?
+	p := product_groups.In(vocab.InGroup).Tag("product").Save(vocab.Label, "name")
+
+	// the customer's own purchases are no evidence, whatever the exclusion policy
+	p = boughtByWithin(p, opts).Except(current_customer).Tag("customer").Save(vocab.Firstname, "client_name") // c2
+
+	// customers that bought the same articles as this customer
+	s := seed{
+		products: customer_articles,
+		buyers:   customer_articles.FollowReverse(bought).Unique(),
+		own:      true,
+	}
+
+	// the exclusion policy is applied to the collected candidates instead of with Except,
+	// so it does not depend on where in the path the purchases are removed
+	excluded, err := excludedFor(ctx, store, customer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations, err := rank(ctx, store, p, s, excluded, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withFallback(ctx, store, recommendations, customer_articles, excluded, opts)
+}
+
+// ForProduct recommends the products bought (within the time window of the options) by
+// customers who also bought the given product:
+//
+//	product1 <- c2 -> products2 (- product1)
+//
+// With Options.SameGroup only products from the same group as the given product are considered:
+//
+//	product1 -> group <- products2 (- product1) <- c2 (+ product_id)
+//
+// With Options.TextWeight the co-purchase scores are blended with the text similarity of the
+// products, see SimilarProducts. When there are too few co-purchases the result is filled up
+// with bestsellers from the product's group, global bestsellers and the newest products.
+func ForProduct(ctx context.Context, store *cayley.Handle, product_id quad.Value, opts Options) (ProductRecommendations, error) {
+	p, s := productPath(store, product_id, opts)
+
+	excluded := blocklist(opts)
+	excluded[vocab.String(product_id)] = struct{}{}
+
+	var recommendations ProductRecommendations
+	if opts.TextWeight > 0 {
+		candidates, err := scored(ctx, store, p, s, excluded, opts)
+		if err != nil {
+			return nil, err
+		}
+		ix := opts.TextIndex
+		if ix == nil {
+			ix, err = NewTextIndex(ctx, store)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := blendText(ix, candidates, product_id, excluded, opts.TextWeight); err != nil {
+			return nil, err
+		}
+		recommendations, err = finish(ctx, store, candidates, s, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := describedLike(ctx, store, recommendations, product_id); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		recommendations, err = rank(ctx, store, p, s, excluded, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return withFallback(ctx, store, recommendations, s.products, excluded, opts)
+}
+
+// productPath returns the co-purchase path of ForProduct and its seed
+func productPath(store *cayley.Handle, product_id quad.Value, opts Options) (*path.Path, seed) {
+	// start from the product
+	current_product := cayley.StartPath(store, product_id)
+
+	var p *path.Path
+	if opts.SameGroup {
+		product_groups := current_product.Out(vocab.InGroup).Unique()
+
+		// what did they buy?
+		p = product_groups.In(vocab.InGroup).Except(current_product).Tag("product").Save(vocab.Label, "name")
+
+		// who bought it that also bought the same product
+		p = boughtByWithin(p, opts).And(current_product.FollowReverse(bought)).Tag("customer").Save(vocab.Firstname, "client_name") // c2
+	} else {
+		// who bought the product?
+		p = current_product.FollowReverse(bought).Tag("customer").Save(vocab.Firstname, "client_name") // c2
+
+		// and what else did they buy?
+		p = boughtWithin(p, opts).Except(current_product).Tag("product").Save(vocab.Label, "name")
+	}
+
+	return p, seed{products: current_product, buyers: current_product.FollowReverse(bought)}
+}
+
+// seed is what the recommendations are made for
+type seed struct {
+	// products are the customer's purchases or the product itself
+	products *path.Path
+	// buyers are the customers that bought the seed products
+	buyers *path.Path
+	// own is true when the seed products were bought by the customer we recommend for
+	own bool
+}
+
+// candidate is a product found by a recommendation path
+type candidate struct {
+	product quad.Value
+	rec     ProductRecommendation
+	// weight is the sum of the recency weights of the rows counted in rec.Count
+	weight float64
+	// customers are the names of the customers in the rows, by id
+	customers map[string]string
+}
+
+// rank collects the candidates of a path tagged with "product", "name", "customer" and
+// "client_name", drops the excluded product ids, scores and explains them and returns them
+// best first
+func rank(ctx context.Context, store *cayley.Handle, p *path.Path, s seed, excluded map[string]struct{}, opts Options) (ProductRecommendations, error) {
+	candidates, err := scored(ctx, store, p, s, excluded, opts)
+	if err != nil {
+		return nil, err
+	}
+	return finish(ctx, store, candidates, s, opts)
+}
+
+// scored collects the candidates of a path, drops the excluded product ids and scores them
+func scored(ctx context.Context, store *cayley.Handle, p *path.Path, s seed, excluded map[string]struct{}, opts Options) (map[string]*candidate, error) {
+	candidates, err := collect(ctx, p, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for id := range excluded {
+		delete(candidates, id)
+	}
+
+	if err := score(ctx, store, s.buyers, candidates, opts.Strategy); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// finish selects the page of candidates asked for in the options and explains them
+func finish(ctx context.Context, store *cayley.Handle, candidates map[string]*candidate, s seed, opts Options) (ProductRecommendations, error) {
+	recommendations := make(ProductRecommendations, 0, len(candidates))
+	for _, c := range candidates {
+		recommendations = append(recommendations, c.rec)
+	}
+	recommendations, err := selectPage(ctx, store, recommendations, s.products, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := explain(ctx, store, recommendations, candidates, s); err != nil {
+		return nil, err
+	}
+	return recommendations, nil
+}
+
+// collect counts the rows of a path tagged with "product" and "name" per product,
+// and sums their recency weight using the purchase time tagged "at"
+func collect(ctx context.Context, p *path.Path, opts Options) (map[string]*candidate, error) {
+	candidates := make(map[string]*candidate)
+
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		c, ok := candidates[id]
+		if !ok {
+			c = &candidate{
+				product: m["product"],
+				rec: ProductRecommendation{
+					ProductID: id,
+					Name:      vocab.String(m["name"]),
+					Tier:      TierCoPurchase,
+				},
+				customers: make(map[string]string),
+			}
+			candidates[id] = c
+		}
+		c.rec.Count++
+		c.weight += recencyWeight(m["at"], opts)
+		if customer := m["customer"]; customer != nil {
+			c.customers[vocab.String(customer)] = vocab.String(m["client_name"])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
@@ -0,0 +1,88 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func TestForCustomer(t *testing.T) {
+	store := seededStore(t)
+
+	tests := []struct {
+		name     string
+		customer string
+		opts     Options
+		want     []string
+		counts   []int32
+	}{
+		{"purchased", casper, Options{NoFallback: true}, []string{monitor}, []int32{1}},
+		// Casper is the only one who bought the harddrive, so it has no evidence
+		{"none", casper, Options{Exclusion: ExcludeNone, NoFallback: true}, []string{walkman, monitor, pencil}, []int32{2, 1, 1}},
+		{"john none", john, Options{Exclusion: ExcludeNone, NoFallback: true}, []string{walkman, harddrive}, []int32{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs, err := ForCustomer(context.TODO(), store, quad.IRI(tt.customer), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(recs); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("recommended %v, want %v", got, tt.want)
+			}
+			for i, r := range recs {
+				if r.Count != tt.counts[i] {
+					t.Errorf("%s has count %d, want %d", r.Name, r.Count, tt.counts[i])
+				}
+				if r.Explanation == nil {
+					t.Errorf("%s is not explained", r.Name)
+					continue
+				}
+				for _, c := range r.Explanation.Customers {
+					if c == tt.customer {
+						t.Errorf("%s is explained by the customer: %q", r.Name, r.Explanation.Text)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestForCustomerUndatedPurchases(t *testing.T) {
+	store := seededStore(t)
+	addUndated(t, store, john, pen)
+
+	tests := []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{"no window", Options{}, true},
+		{"half-life", Options{HalfLife: 24 * time.Hour, Now: date(2017, 3, 21)}, true},
+		{"window", Options{Since: date(2017, 1, 1)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.NoFallback = true
+			recs, err := ForCustomer(context.TODO(), store, quad.IRI(alice), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, r := range recs {
+				if r.ProductID == pen {
+					found = true
+					if r.Count != 1 {
+						t.Errorf("pen has count %d, want 1", r.Count)
+					}
+				}
+			}
+			if found != tt.want {
+				t.Errorf("pen recommended %v, want %v: %v", found, tt.want, ids(recs))
+			}
+		})
+	}
+}
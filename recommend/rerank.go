@@ -0,0 +1,323 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Rules re-rank the recommendations before a page is selected. They are usually loaded
+// from a JSON file with LoadRules:
+//
+//	{
+//		"pin": ["2017979d-516a-4bac-a55e-b71c4dcb2364"],
+//		"boost": {"3017979d-516a-4bac-a55e-b71c4dcb2358": 1.5},
+//		"bury": ["2017979d-516a-4bac-a55e-b71c4dcb2351"],
+//		"price_band": {"min": 0.5, "max": 2},
+//		"hide_out_of_stock": true,
+//		"diversity": 0.3
+//	}
+type Rules struct {
+	// Pin moves these products to the top of the recommendations, in this order, when they are recommended
+	Pin []string `json:"pin,omitempty"`
+	// Boost multiplies the score of the products, or of the products in the groups, with these ids
+	Boost map[string]float64 `json:"boost,omitempty"`
+	// Bury moves the products, or the products in the groups, with these ids to the bottom
+	Bury []string `json:"bury,omitempty"`
+	// PriceBand only keeps products priced relative to the seed products
+	PriceBand *PriceBand `json:"price_band,omitempty"`
+	// HideOutOfStock drops the products with a stock of 0 or less
+	HideOutOfStock bool `json:"hide_out_of_stock,omitempty"`
+	// Diversity trades relevance for variety in groups with maximal marginal relevance,
+	// from 0 (ranked by score only) to 1 (a new group whenever possible)
+	Diversity float64 `json:"diversity,omitempty"`
+}
+
+// PriceBand keeps products priced between Min and Max times the average price of the seed
+// products, a zero bound leaves that side open. Products without a price are kept.
+type PriceBand struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// LoadRules reads re-ranking rules from a JSON file
+func LoadRules(file string) (*Rules, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := &Rules{}
+	if err := json.NewDecoder(f).Decode(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// attributes are what the rules need to know about a product
+type attributes struct {
+	groups  []string
+	price   float64
+	priced  bool
+	stock   float64
+	stocked bool
+}
+
+// selectPage leaves out unavailable products and applies Options.Rules, the minimum filters,
+// the limit and the offset. Prices are relative to the seed products.
+func selectPage(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, seedProducts *path.Path, opts Options) (ProductRecommendations, error) {
+	kept, attrs, err := applyRules(ctx, store, recommendations, seedProducts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		return paginate(kept, opts), nil
+	}
+
+	offset, n := bounds(opts)
+	if n == 0 || n > len(kept) {
+		n = len(kept)
+	}
+	if rules.Diversity > 0 {
+		kept = diversify(kept, attrs, rules.Diversity)
+	} else {
+		sort.Sort(kept)
+	}
+
+	kept = moveIDs(kept, attrs, rules.Bury, false)
+	kept = moveIDs(kept, attrs, rules.Pin, true)
+
+	kept = kept[:n]
+	if offset >= len(kept) {
+		return ProductRecommendations{}, nil
+	}
+	return kept[offset:], nil
+}
+
+// applyRules leaves out unavailable products, the products Options.Rules hides and the ones
+// below the minimum filters, and boosts the scores of the rest. The order is kept. It also
+// returns the attributes the rules were checked against, nil without rules.
+func applyRules(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, seedProducts *path.Path, opts Options) (ProductRecommendations, map[string]attributes, error) {
+	recommendations, err := onlyAvailable(ctx, store, recommendations, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		kept := recommendations[:0]
+		for _, r := range recommendations {
+			if r.Count >= opts.MinCount && r.Score >= opts.MinScore {
+				kept = append(kept, r)
+			}
+		}
+		return kept, nil, nil
+	}
+
+	attrs, err := attributesOf(ctx, store, recommendations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var low, high float64
+	if band := rules.PriceBand; band != nil {
+		seedPrice, err := averagePrice(ctx, seedProducts)
+		if err != nil {
+			return nil, nil, err
+		}
+		low, high = band.Min*seedPrice, band.Max*seedPrice
+	}
+
+	kept := recommendations[:0]
+	for _, r := range recommendations {
+		a := attrs[r.ProductID]
+		if rules.HideOutOfStock && a.stocked && a.stock <= 0 {
+			continue
+		}
+		if a.priced && ((low > 0 && a.price < low) || (high > 0 && a.price > high)) {
+			continue
+		}
+		if boost, ok := rules.Boost[r.ProductID]; ok {
+			r.Score *= boost
+		}
+		for _, g := range a.groups {
+			if boost, ok := rules.Boost[g]; ok {
+				r.Score *= boost
+			}
+		}
+		if r.Count < opts.MinCount || r.Score < opts.MinScore {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, attrs, nil
+}
+
+// diversify orders the recommendations by maximal marginal relevance: every next one has
+// the best (1 - diversity) * score - diversity * similarity to the ones before it, where the
+// scores are scaled to 0..1 and products in the same group have a similarity of 1
+func diversify(recommendations ProductRecommendations, attrs map[string]attributes, diversity float64) ProductRecommendations {
+	sort.Sort(recommendations)
+
+	var max float64
+	for _, r := range recommendations {
+		if r.Score > max {
+			max = r.Score
+		}
+	}
+
+	seen := make(map[string]bool)
+	rest := append(ProductRecommendations{}, recommendations...)
+	ordered := make(ProductRecommendations, 0, len(rest))
+	for len(rest) > 0 {
+		best, bestValue := 0, 0.0
+		for i, r := range rest {
+			relevance := 0.0
+			if max > 0 {
+				relevance = r.Score / max
+			}
+			similarity := 0.0
+			for _, g := range attrs[r.ProductID].groups {
+				if seen[g] {
+					similarity = 1
+				}
+			}
+			// rest is sorted, so ties keep the better recommendation
+			if value := (1-diversity)*relevance - diversity*similarity; i == 0 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		r := rest[best]
+		ordered = append(ordered, r)
+		for _, g := range attrs[r.ProductID].groups {
+			seen[g] = true
+		}
+		rest = append(rest[:best], rest[best+1:]...)
+	}
+	return ordered
+}
+
+// moveIDs moves the recommendations whose product or group is listed to the top, in the
+// order of ids, or to the bottom
+func moveIDs(recommendations ProductRecommendations, attrs map[string]attributes, ids []string, top bool) ProductRecommendations {
+	if len(ids) == 0 {
+		return recommendations
+	}
+	rank := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	position := func(r ProductRecommendation) (int, bool) {
+		if i, ok := rank[r.ProductID]; ok {
+			return i, true
+		}
+		for _, g := range attrs[r.ProductID].groups {
+			if i, ok := rank[g]; ok {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	var moved, others ProductRecommendations
+	for _, r := range recommendations {
+		if _, ok := position(r); ok {
+			moved = append(moved, r)
+		} else {
+			others = append(others, r)
+		}
+	}
+	if !top {
+		return append(others, moved...)
+	}
+
+	sort.SliceStable(moved, func(i, j int) bool {
+		a, _ := position(moved[i])
+		b, _ := position(moved[j])
+		return a < b
+	})
+	return append(moved, others...)
+}
+
+// attributesOf reads the groups, price and stock of the recommended products
+func attributesOf(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations) (map[string]attributes, error) {
+	attrs := make(map[string]attributes, len(recommendations))
+	if len(recommendations) == 0 {
+		return attrs, nil
+	}
+
+	ids := make([]quad.Value, 0, len(recommendations))
+	for _, r := range recommendations {
+		ids = append(ids, quad.IRI(r.ProductID))
+	}
+	products := cayley.StartPath(store, ids...).Tag("product")
+
+	err := products.Out(vocab.InGroup).Tag("group").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		a := attrs[id]
+		a.groups = append(a.groups, vocab.String(m["group"]))
+		attrs[id] = a
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = products.Out(vocab.Price).Tag("price").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		a := attrs[id]
+		a.price, a.priced = toFloat(m["price"])
+		attrs[id] = a
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = products.Out(vocab.Stock).Tag("stock").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		a := attrs[id]
+		a.stock, a.stocked = toFloat(m["stock"])
+		attrs[id] = a
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// averagePrice returns the average price of the products of a path, 0 when none has a price
+func averagePrice(ctx context.Context, products *path.Path) (float64, error) {
+	var sum float64
+	var n int
+	err := products.Out(vocab.Price).Iterate(ctx).EachValue(nil, func(v quad.Value) {
+		if price, ok := toFloat(v); ok {
+			sum += price
+			n++
+		}
+	})
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	return sum / float64(n), nil
+}
+
+// toFloat returns the value of a numeric quad value
+func toFloat(v quad.Value) (float64, bool) {
+	switch t := v.(type) {
+	case quad.Float:
+		return float64(t), true
+	case quad.Int:
+		return float64(t), true
+	default:
+		return 0, false
+	}
+}
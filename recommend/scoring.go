@@ -0,0 +1,159 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// Strategy names the way co-purchase evidence is turned into a score
+type Strategy string
+
+const (
+	// StrategyCount scores by the raw number of co-purchase rows
+	StrategyCount Strategy = "count"
+	// StrategyJaccard scores by |X ∩ Y| / |X ∪ Y| over the customers who bought the seed (X) and the candidate (Y)
+	StrategyJaccard Strategy = "jaccard"
+	// StrategyCosine scores by |X ∩ Y| / sqrt(|X| |Y|)
+	StrategyCosine Strategy = "cosine"
+	// StrategyLift scores by how much more often X and Y are bought together than by chance: |X ∩ Y| N / (|X| |Y|)
+	StrategyLift Strategy = "lift"
+)
+
+// Strategies lists all known scoring strategies
+var Strategies = []Strategy{StrategyCount, StrategyJaccard, StrategyCosine, StrategyLift}
+
+// ParseStrategy returns the strategy with the given name, an empty name gives StrategyCount
+func ParseStrategy(name string) (Strategy, error) {
+	if name == "" {
+		return StrategyCount, nil
+	}
+	for _, s := range Strategies {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scoring strategy %q", name)
+}
+
+// score fills in Score and Strategy of every candidate. seedBuyers are the customers
+// that bought the seed the candidates are recommended for. Scores are multiplied by the
+// average recency weight of the candidate's co-purchases, so for StrategyCount the
+// score is the decayed count.
+func score(ctx context.Context, store *cayley.Handle, seedBuyers *path.Path, candidates map[string]*candidate, strategy Strategy) error {
+	if strategy == "" {
+		strategy = StrategyCount
+	}
+
+	if strategy == StrategyCount {
+		for _, c := range candidates {
+			c.rec.Score = c.weight
+			c.rec.Strategy = strategy
+		}
+		return nil
+	}
+
+	seedSet, err := valueSet(ctx, seedBuyers)
+	if err != nil {
+		return err
+	}
+
+	buyers, err := buyersOf(ctx, store, candidates)
+	if err != nil {
+		return err
+	}
+
+	var total int
+	if strategy == StrategyLift {
+		total, err = countBuyers(ctx, store)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, c := range candidates {
+		var both int
+		for customer := range buyers[id] {
+			if _, ok := seedSet[customer]; ok {
+				both++
+			}
+		}
+		x, y, co := float64(len(seedSet)), float64(len(buyers[id])), float64(both)
+
+		var s float64
+		switch strategy {
+		case StrategyJaccard:
+			if union := x + y - co; union > 0 {
+				s = co / union
+			}
+		case StrategyCosine:
+			if x > 0 && y > 0 {
+				s = co / math.Sqrt(x*y)
+			}
+		case StrategyLift:
+			if x > 0 && y > 0 {
+				s = co * float64(total) / (x * y)
+			}
+		default:
+			return fmt.Errorf("unknown scoring strategy %q", strategy)
+		}
+		if c.rec.Count > 0 {
+			s *= c.weight / float64(c.rec.Count)
+		}
+		c.rec.Score = s
+		c.rec.Strategy = strategy
+	}
+	return nil
+}
+
+// buyersOf returns the distinct customers that bought each candidate, keyed by product id
+func buyersOf(ctx context.Context, store *cayley.Handle, candidates map[string]*candidate) (map[string]map[string]struct{}, error) {
+	buyers := make(map[string]map[string]struct{}, len(candidates))
+	if len(candidates) == 0 {
+		return buyers, nil
+	}
+
+	products := make([]quad.Value, 0, len(candidates))
+	for _, c := range candidates {
+		products = append(products, c.product)
+	}
+
+	p := cayley.StartPath(store, products...).Tag("product").FollowReverse(bought).Tag("customer")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id := vocab.String(m["product"])
+		if buyers[id] == nil {
+			buyers[id] = make(map[string]struct{})
+		}
+		buyers[id][vocab.String(m["customer"])] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buyers, nil
+}
+
+// countBuyers returns the number of distinct customers that bought anything
+func countBuyers(ctx context.Context, store *cayley.Handle) (int, error) {
+	buyers, err := valueSet(ctx, cayley.StartPath(store).Out(vocab.HasPurchase).In(vocab.HasPurchase).Unique())
+	if err != nil {
+		return 0, err
+	}
+	return len(buyers), nil
+}
+
+// valueSet returns the distinct values a path resolves to
+func valueSet(ctx context.Context, p *path.Path) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	err := p.Iterate(ctx).EachValue(nil, func(v quad.Value) {
+		set[vocab.String(v)] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
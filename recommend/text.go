@@ -0,0 +1,203 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// StrategyText is the strategy of the recommendations made by SimilarProducts
+const StrategyText Strategy = "text"
+
+// StrategyHybrid is the strategy of ForProduct when Options.TextWeight is set
+const StrategyHybrid Strategy = "hybrid"
+
+// TierText is a recommendation found by the similarity of the product texts
+const TierText Tier = "similar_text"
+
+// TextIndex is a TF-IDF index over the label and description of the products
+type TextIndex struct {
+	// vectors are the unit length TF-IDF vectors of the products, by id
+	vectors map[string]map[string]float64
+	names   map[string]string
+}
+
+// NewTextIndex indexes the label and desc of every product in the store
+func NewTextIndex(ctx context.Context, store *cayley.Handle) (*TextIndex, error) {
+	ix := &TextIndex{vectors: make(map[string]map[string]float64), names: make(map[string]string)}
+	products := cayley.StartPath(store, vocab.ClassProduct).In(vocab.Type)
+
+	terms := make(map[string]map[string]int)
+	add := func(id, text string) {
+		if terms[id] == nil {
+			terms[id] = make(map[string]int)
+		}
+		for _, t := range tokenize(text) {
+			terms[id][t]++
+		}
+	}
+
+	err := products.Tag("product").Out(vocab.Label).Tag("name").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		id, name := vocab.String(m["product"]), vocab.String(m["name"])
+		ix.names[id] = name
+		add(id, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = products.Tag("product").Out(vocab.Desc).Tag("desc").Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		add(vocab.String(m["product"]), vocab.String(m["desc"]))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// document frequencies
+	df := make(map[string]int)
+	for _, counts := range terms {
+		for t := range counts {
+			df[t]++
+		}
+	}
+
+	// terms in every product, like "description", get an idf of 0 and drop out
+	n := float64(len(terms))
+	for id, counts := range terms {
+		v := make(map[string]float64)
+		var norm float64
+		for t, tf := range counts {
+			w := (1 + math.Log(float64(tf))) * math.Log(n/float64(df[t]))
+			if w > 0 {
+				v[t] = w
+				norm += w * w
+			}
+		}
+		norm = math.Sqrt(norm)
+		for t := range v {
+			v[t] /= norm
+		}
+		ix.vectors[id] = v
+	}
+	return ix, nil
+}
+
+// Similarity returns the cosine similarity of the texts of two products, from 0 to 1
+func (ix *TextIndex) Similarity(a, b string) float64 {
+	va, vb := ix.vectors[a], ix.vectors[b]
+	if len(vb) < len(va) {
+		va, vb = vb, va
+	}
+	var s float64
+	for t, w := range va {
+		s += w * vb[t]
+	}
+	return s
+}
+
+// similarTo returns the similarity of every other product with some text in common
+func (ix *TextIndex) similarTo(id string) map[string]float64 {
+	similar := make(map[string]float64)
+	for other := range ix.vectors {
+		if other == id {
+			continue
+		}
+		if s := ix.Similarity(id, other); s > 0 {
+			similar[other] = s
+		}
+	}
+	return similar
+}
+
+// SimilarProducts recommends the products whose label and description are most like the
+// ones of the given product. Blocklist, pagination and fallback work as for ForProduct.
+func SimilarProducts(ctx context.Context, store *cayley.Handle, ix *TextIndex, product_id quad.Value, opts Options) (ProductRecommendations, error) {
+	id := vocab.String(product_id)
+	excluded := blocklist(opts)
+	excluded[id] = struct{}{}
+
+	var recommendations ProductRecommendations
+	for other, s := range ix.similarTo(id) {
+		if _, ok := excluded[other]; ok {
+			continue
+		}
+		recommendations = append(recommendations, ProductRecommendation{
+			ProductID:   other,
+			Name:        ix.names[other],
+			Score:       s,
+			Strategy:    StrategyText,
+			Tier:        TierText,
+			Explanation: &Explanation{Text: fmt.Sprintf("described like %s", ix.names[id]), Products: []string{id}},
+		})
+	}
+
+	product := cayley.StartPath(store, product_id)
+	recommendations, err := selectPage(ctx, store, recommendations, product, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withFallback(ctx, store, recommendations, product, excluded, opts)
+}
+
+// blendText mixes the text similarity with the seed product into the co-purchase scores of
+// the candidates: (1 - weight) * score / max score + weight * similarity. Products that were
+// never bought together but have similar texts become candidates as well.
+func blendText(ix *TextIndex, candidates map[string]*candidate, product_id quad.Value, excluded map[string]struct{}, weight float64) error {
+	id := vocab.String(product_id)
+	similar := ix.similarTo(id)
+
+	var max float64
+	for _, c := range candidates {
+		max = math.Max(max, c.rec.Score)
+	}
+
+	for other := range similar {
+		if _, ok := excluded[other]; ok {
+			continue
+		}
+		if _, ok := candidates[other]; !ok {
+			candidates[other] = &candidate{
+				product:   quad.IRI(other),
+				rec:       ProductRecommendation{ProductID: other, Name: ix.names[other], Tier: TierText},
+				customers: make(map[string]string),
+			}
+		}
+	}
+
+	for other, c := range candidates {
+		var normalized float64
+		if max > 0 {
+			normalized = c.rec.Score / max
+		}
+		c.rec.Score = (1-weight)*normalized + weight*similar[other]
+		c.rec.Strategy = StrategyHybrid
+	}
+	return nil
+}
+
+// describedLike explains the text only recommendations of a hybrid result
+func describedLike(ctx context.Context, store *cayley.Handle, recommendations ProductRecommendations, product_id quad.Value) error {
+	seed, err := names(ctx, cayley.StartPath(store, product_id))
+	if err != nil {
+		return err
+	}
+	for i := range recommendations {
+		r := &recommendations[i]
+		if r.Tier == TierText && (r.Explanation == nil || r.Explanation.Text == "") {
+			r.Explanation = &Explanation{Text: "described like " + join(seed), Products: []string{vocab.String(product_id)}}
+		}
+	}
+	return nil
+}
+
+// tokenize splits a text in lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
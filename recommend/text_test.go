@@ -0,0 +1,52 @@
+package recommend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/model"
+)
+
+func TestForProductTextIndex(t *testing.T) {
+	store := seededStore(t)
+	ix, err := NewTextIndex(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a product added after indexing is only found when the products are indexed again
+	w := graph.NewWriter(store)
+	discman := "2017979d-516a-4bac-a55e-b71c4dcb2399"
+	if err := model.SaveProduct(w, model.Product{ID: quad.IRI(discman), Label: "Walkman CD", Desc: "Walkman for CDs", Price: 40}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		index *TextIndex
+		want  bool
+	}{
+		{"shared index", ix, false},
+		{"indexed per call", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs, err := ForProduct(context.TODO(), store, quad.IRI(walkman), Options{TextWeight: 0.5, TextIndex: tt.index, NoFallback: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, r := range recs {
+				found = found || r.ProductID == discman
+			}
+			if found != tt.want {
+				t.Errorf("new product recommended %v, want %v: %v", found, tt.want, ids(recs))
+			}
+		})
+	}
+}
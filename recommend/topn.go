@@ -0,0 +1,80 @@
+package recommend
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// worstFirst is a heap of recommendations with the worst one on top
+type worstFirst ProductRecommendations
+
+func (h worstFirst) Len() int           { return len(h) }
+func (h worstFirst) Less(i, j int) bool { return ProductRecommendations(h).Less(j, i) }
+func (h worstFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *worstFirst) Push(x interface{}) {
+	*h = append(*h, x.(ProductRecommendation))
+}
+
+func (h *worstFirst) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// topN returns the n best recommendations, best first. It keeps a heap of
+// at most n items instead of sorting all candidates.
+func topN(recommendations ProductRecommendations, n int) ProductRecommendations {
+	if n <= 0 || n >= len(recommendations) {
+		sort.Sort(recommendations)
+		return recommendations
+	}
+
+	h := make(worstFirst, 0, n)
+	for _, r := range recommendations {
+		if len(h) < n {
+			heap.Push(&h, r)
+			continue
+		}
+		if better(r, h[0]) {
+			h[0] = r
+			heap.Fix(&h, 0)
+		}
+	}
+
+	best := ProductRecommendations(h)
+	sort.Sort(best)
+	return best
+}
+
+// paginate applies the limit, offset and minimum filters of the options
+func paginate(recommendations ProductRecommendations, opts Options) ProductRecommendations {
+	filtered := recommendations[:0]
+	for _, r := range recommendations {
+		if r.Count < opts.MinCount || r.Score < opts.MinScore {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+
+	offset, end := bounds(opts)
+	filtered = topN(filtered, end)
+
+	if offset >= len(filtered) {
+		return ProductRecommendations{}
+	}
+	return filtered[offset:]
+}
+
+// bounds returns the offset of the page and the number of recommendations up to its end,
+// 0 for all. Negative offsets and limits count as 0.
+func bounds(opts Options) (offset, end int) {
+	if opts.Offset > 0 {
+		offset = opts.Offset
+	}
+	if opts.Limit > 0 {
+		end = offset + opts.Limit
+	}
+	return offset, end
+}
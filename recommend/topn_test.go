@@ -0,0 +1,106 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func ids(recommendations ProductRecommendations) []string {
+	list := []string{}
+	for _, r := range recommendations {
+		list = append(list, r.ProductID)
+	}
+	return list
+}
+
+func TestTopN(t *testing.T) {
+	recommendations := ProductRecommendations{
+		{ProductID: "a", Name: "Walkman", Count: 1, Score: 1},
+		{ProductID: "b", Name: "Pencil", Count: 3, Score: 2},
+		{ProductID: "c", Name: "Blanket", Count: 2, Score: 2},
+		{ProductID: "d", Name: "Blanket", Count: 2, Score: 2},
+		{ProductID: "e", Name: "Monitor", Count: 5, Score: 0.5},
+		{ProductID: "f", Name: "Aardvark", Count: 3, Score: 2},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		// score, then count, then name, then id
+		{"all", 0, []string{"f", "b", "c", "d", "a", "e"}},
+		{"ties on count are ordered by name", 2, []string{"f", "b"}},
+		{"ties on name are ordered by id", 4, []string{"f", "b", "c", "d"}},
+		{"cut between equal names", 3, []string{"f", "b", "c"}},
+		{"n equals the number of items", 6, []string{"f", "b", "c", "d", "a", "e"}},
+		{"n larger than the number of items", 10, []string{"f", "b", "c", "d", "a", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append(ProductRecommendations{}, recommendations...)
+			if got := ids(topN(input, tt.n)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("topN(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	recommendations := ProductRecommendations{
+		{ProductID: "a", Count: 1, Score: 1},
+		{ProductID: "b", Count: 3, Score: 3},
+		{ProductID: "c", Count: 2, Score: 2},
+		{ProductID: "d", Count: 2, Score: 2},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"all", Options{}, []string{"b", "c", "d", "a"}},
+		{"limit", Options{Limit: 2}, []string{"b", "c"}},
+		{"offset", Options{Offset: 1, Limit: 2}, []string{"c", "d"}},
+		{"limit past the end", Options{Offset: 3, Limit: 5}, []string{"a"}},
+		{"offset past the end", Options{Offset: 4}, []string{}},
+		{"negative offset", Options{Offset: -2, Limit: 2}, []string{"b", "c"}},
+		{"negative limit", Options{Offset: 1, Limit: -1}, []string{"c", "d", "a"}},
+		{"min count", Options{MinCount: 2}, []string{"b", "c", "d"}},
+		{"min score", Options{MinScore: 2.5}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append(ProductRecommendations{}, recommendations...)
+			if got := ids(paginate(input, tt.opts)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate(%+v) = %v, want %v", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectPageNegativeBounds(t *testing.T) {
+	store := seededStore(t)
+	recommendations := ProductRecommendations{
+		{ProductID: walkman, Name: "Walkman", Count: 1, Score: 1},
+		{ProductID: monitor, Name: "Monitor", Count: 2, Score: 2},
+	}
+	seedProducts := cayley.StartPath(store, quad.IRI(pen))
+
+	for _, opts := range []Options{
+		{Offset: -1, Limit: -1},
+		{Offset: -1, Limit: -1, Rules: &Rules{}},
+	} {
+		got, err := selectPage(context.TODO(), store, append(ProductRecommendations{}, recommendations...), seedProducts, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{monitor, walkman}; !reflect.DeepEqual(ids(got), want) {
+			t.Errorf("selectPage(%+v) = %v, want %v", opts, ids(got), want)
+		}
+	}
+}
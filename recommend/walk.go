@@ -0,0 +1,164 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/jtorvald/cayley-demo/vocab"
+)
+
+// StrategyRandomWalk is the strategy of the recommendations made by RandomWalkForCustomer
+// and RandomWalkForProduct. It is not a scoring strategy for the co-purchase paths.
+const StrategyRandomWalk Strategy = "random_walk"
+
+// TierRandomWalk is a recommendation found by a random walk
+const TierRandomWalk Tier = "random_walk"
+
+// WalkConfig configures a random walk with restart
+type WalkConfig struct {
+	// Restart is the probability to jump back to the seed at every step, between 0 and 1.
+	// It defaults to 0.15, values below 0 or from 1 on are an error.
+	Restart float64
+	// Steps is the number of steps walked, defaults to 100000
+	Steps int
+	// Seed seeds the random walk, the same seed gives the same recommendations
+	Seed int64
+	// Graph is the graph to walk, load it once with LoadWalkGraph for many walks with the
+	// same time window. Without it every walk loads the graph from the store.
+	Graph *WalkGraph
+}
+
+// RandomWalkForCustomer recommends products by personalized PageRank: a random walk
+// from the customer over the customer <-> product purchases and the product <-> group
+// edges, restarting at the customer. Products are scored by the fraction of steps spent
+// on them. Exclusion, time window, pagination and fallback work as for ForCustomer.
+func RandomWalkForCustomer(ctx context.Context, store *cayley.Handle, customer quad.Value, cfg WalkConfig, opts Options) (ProductRecommendations, error) {
+	excluded, err := excludedFor(ctx, store, customer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return randomWalk(ctx, store, customer, cayley.StartPath(store, customer).Follow(bought), excluded, cfg, opts)
+}
+
+// RandomWalkForProduct recommends products by a random walk with restart from the product
+func RandomWalkForProduct(ctx context.Context, store *cayley.Handle, product_id quad.Value, cfg WalkConfig, opts Options) (ProductRecommendations, error) {
+	excluded := blocklist(opts)
+	excluded[vocab.String(product_id)] = struct{}{}
+
+	return randomWalk(ctx, store, product_id, cayley.StartPath(store, product_id), excluded, cfg, opts)
+}
+
+// WalkGraph is the undirected customer - product - group graph the walk runs on
+type WalkGraph struct {
+	neighbours map[string][]string
+	products   map[string]bool
+}
+
+func (g *WalkGraph) link(a, b string) {
+	g.neighbours[a] = append(g.neighbours[a], b)
+	g.neighbours[b] = append(g.neighbours[b], a)
+}
+
+// LoadWalkGraph reads the purchases (within the time window of the options) and groups
+func LoadWalkGraph(ctx context.Context, store *cayley.Handle, opts Options) (*WalkGraph, error) {
+	g := &WalkGraph{neighbours: make(map[string][]string), products: make(map[string]bool)}
+
+	p := boughtWithin(cayley.StartPath(store).Tag("customer"), opts).Tag("product")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		product := vocab.String(m["product"])
+		g.products[product] = true
+		g.link(vocab.String(m["customer"]), product)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p = cayley.StartPath(store).Tag("product").Out(vocab.InGroup).Tag("group")
+	err = p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		product := vocab.String(m["product"])
+		g.products[product] = true
+		g.link(product, vocab.String(m["group"]))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// sorted neighbours make the walk only depend on the seed
+	for _, n := range g.neighbours {
+		sort.Strings(n)
+	}
+	return g, nil
+}
+
+// randomWalk walks from start, the seed products are the ones the walk starts from or next to
+func randomWalk(ctx context.Context, store *cayley.Handle, start quad.Value, seedProducts *path.Path, excluded map[string]struct{}, cfg WalkConfig, opts Options) (ProductRecommendations, error) {
+	if cfg.Restart < 0 || cfg.Restart >= 1 {
+		return nil, fmt.Errorf("restart probability %v is not in [0, 1)", cfg.Restart)
+	}
+	if cfg.Restart == 0 {
+		cfg.Restart = 0.15
+	}
+	if cfg.Steps <= 0 {
+		cfg.Steps = 100000
+	}
+
+	var err error
+	g := cfg.Graph
+	if g == nil {
+		g, err = LoadWalkGraph(ctx, store, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	origin := vocab.String(start)
+	visits := make(map[string]int)
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	current := origin
+	for i := 0; i < cfg.Steps; i++ {
+		next := g.neighbours[current]
+		if len(next) == 0 || rnd.Float64() < cfg.Restart {
+			current = origin
+			continue
+		}
+		current = next[rnd.Intn(len(next))]
+		if g.products[current] {
+			visits[current]++
+		}
+	}
+
+	var ids []quad.Value
+	for id := range visits {
+		if _, ok := excluded[id]; !ok {
+			ids = append(ids, quad.IRI(id))
+		}
+	}
+
+	var recommendations ProductRecommendations
+	if len(ids) > 0 {
+		recommendations, err = withLabels(ctx, cayley.StartPath(store, ids...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range recommendations {
+		r := &recommendations[i]
+		r.Count = int32(visits[r.ProductID])
+		r.Score = float64(visits[r.ProductID]) / float64(cfg.Steps)
+		r.Strategy = StrategyRandomWalk
+		r.Tier = TierRandomWalk
+		r.Explanation = &Explanation{Text: "often reached in a random walk over the purchases"}
+	}
+
+	recommendations, err = selectPage(ctx, store, recommendations, seedProducts, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withFallback(ctx, store, recommendations, seedProducts, excluded, opts)
+}
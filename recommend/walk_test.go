@@ -0,0 +1,48 @@
+package recommend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func TestRandomWalkDeterministic(t *testing.T) {
+	store := seededStore(t)
+	graph, err := LoadWalkGraph(context.TODO(), store, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walk := func(cfg WalkConfig) ProductRecommendations {
+		recs, err := RandomWalkForCustomer(context.TODO(), store, quad.IRI(john), cfg, Options{NoFallback: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return recs
+	}
+	first := walk(WalkConfig{Steps: 2000, Seed: 7})
+	if len(first) == 0 {
+		t.Fatal("the walk recommends nothing")
+	}
+	for _, cfg := range []WalkConfig{
+		{Steps: 2000, Seed: 7},
+		{Steps: 2000, Seed: 7, Graph: graph},
+		{Steps: 2000, Seed: 7, Restart: 0.15},
+	} {
+		if got := walk(cfg); !reflect.DeepEqual(got, first) {
+			t.Errorf("walk with %+v gives %+v, want %+v", cfg, got, first)
+		}
+	}
+}
+
+func TestRandomWalkRestart(t *testing.T) {
+	store := seededStore(t)
+	for _, restart := range []float64{-0.1, 1, 1.5} {
+		_, err := RandomWalkForCustomer(context.TODO(), store, quad.IRI(john), WalkConfig{Restart: restart, Steps: 10}, Options{})
+		if err == nil {
+			t.Errorf("expected an error for restart %v", restart)
+		}
+	}
+}
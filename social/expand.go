@@ -0,0 +1,123 @@
+package social
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// Direction is the direction edges are followed in
+type Direction string
+
+const (
+	// Out follows edges from subject to object
+	Out Direction = "out"
+	// In follows edges from object to subject
+	In Direction = "in"
+	// Both follows edges both ways
+	Both Direction = "both"
+)
+
+// ParseDirection returns the direction with the given name, an empty name gives Out
+func ParseDirection(name string) (Direction, error) {
+	switch d := Direction(name); d {
+	case "":
+		return Out, nil
+	case Out, In, Both:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown direction %q, use out, in or both", name)
+}
+
+// ExpandConfig configures Expand
+type ExpandConfig struct {
+	// Direction defaults to Out
+	Direction Direction
+	// Predicates are the edges that may be followed, all when empty
+	Predicates []quad.Value
+	// MaxDepth is the number of hops from the start node, defaults to 1
+	MaxDepth int
+	// MaxNodes stops the expansion when this many nodes were visited, 0 means no limit
+	MaxNodes int
+	// Inference adds the edges that follow from symmetric and inverse predicates, see LoadInference
+	Inference *Inference
+}
+
+// Edge is a quad of the subgraph
+type Edge struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+	// Inferred is true for an edge that is not stored but follows from the predicate rules
+	Inferred bool `json:"inferred,omitempty"`
+}
+
+// edge returns the quad a step followed
+func (s Step) edge() Edge {
+	if s.Inverse {
+		return Edge{Subject: s.To, Predicate: s.Predicate, Object: s.From, Inferred: s.Inferred}
+	}
+	return Edge{Subject: s.From, Predicate: s.Predicate, Object: s.To, Inferred: s.Inferred}
+}
+
+// Subgraph is what Expand visited
+type Subgraph struct {
+	// Nodes are the visited nodes in the order they were found, the start node first
+	Nodes []string `json:"nodes"`
+	// Depth is the number of hops to each node
+	Depth map[string]int `json:"depth"`
+	// Edges are the followed edges between the visited nodes
+	Edges []Edge `json:"edges"`
+	// Truncated is true when MaxNodes stopped the expansion
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Expand visits the k-hop neighbourhood of a node breadth first. Every node is expanded
+// once, so cycles end the walk: an edge back to a visited node is kept in the subgraph but
+// not followed again.
+func Expand(ctx context.Context, store *cayley.Handle, start quad.Value, cfg ExpandConfig) (*Subgraph, error) {
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 1
+	}
+	direction, err := ParseDirection(string(cfg.Direction))
+	if err != nil {
+		return nil, err
+	}
+
+	origin := quad.ToString(start)
+	g := &Subgraph{Nodes: []string{origin}, Depth: map[string]int{origin: 0}}
+	frontier := []quad.Value{start}
+	seenEdges := make(map[Edge]bool)
+
+	for d := 1; d <= cfg.MaxDepth && len(frontier) > 0 && !g.Truncated; d++ {
+		steps, next, err := cfg.Inference.expand(ctx, store, frontier, cfg.Predicates, direction != In, direction != Out)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for i, s := range steps {
+			if _, ok := g.Depth[s.To]; !ok {
+				if cfg.MaxNodes > 0 && len(g.Nodes) >= cfg.MaxNodes {
+					g.Truncated = true
+					continue
+				}
+				g.Depth[s.To] = d
+				g.Nodes = append(g.Nodes, s.To)
+				frontier = append(frontier, next[i])
+			}
+
+			// an edge found stored and inferred from different nodes is kept as found first
+			e := s.edge()
+			key := e
+			key.Inferred = false
+			if !seenEdges[key] {
+				seenEdges[key] = true
+				g.Edges = append(g.Edges, e)
+			}
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,138 @@
+package social
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func knowsEdge(subject, object string) Edge {
+	return Edge{Subject: subject, Predicate: "knows", Object: object}
+}
+
+func TestExpand(t *testing.T) {
+	store := demoGraph(t)
+	knowsOnly := []quad.Value{predKnows}
+
+	tests := []struct {
+		name      string
+		start     string
+		cfg       ExpandConfig
+		depth     map[string]int
+		edges     []Edge
+		truncated bool
+	}{
+		{
+			"one hop", "jorgent", ExpandConfig{Predicates: knowsOnly},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1},
+			[]Edge{knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc")},
+			false,
+		},
+		{
+			"all predicates", "jorgent", ExpandConfig{},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1, "robertmeta": 1},
+			[]Edge{knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc"), {Subject: "jorgent", Predicate: "drinks_with", Object: "robertmeta"}},
+			false,
+		},
+		{
+			"two hops", "jorgent", ExpandConfig{Predicates: knowsOnly, MaxDepth: 2},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1, "robertmeta": 2},
+			[]Edge{
+				knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc"),
+				knowsEdge("oren", "robertmeta"), knowsEdge("dennwc", "robertmeta"),
+			},
+			false,
+		},
+		{
+			// edges back to visited people are kept but not followed again
+			"cycles", "jorgent", ExpandConfig{Predicates: knowsOnly, MaxDepth: 100},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1, "robertmeta": 2, "barakmich": 3, "betawaffle": 3, "henrocdotnet": 3},
+			[]Edge{
+				knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc"),
+				knowsEdge("oren", "robertmeta"), knowsEdge("dennwc", "robertmeta"),
+				knowsEdge("robertmeta", "barakmich"), knowsEdge("robertmeta", "betawaffle"), knowsEdge("robertmeta", "dennwc"),
+				knowsEdge("robertmeta", "henrocdotnet"), knowsEdge("robertmeta", "oren"),
+				knowsEdge("barakmich", "robertmeta"), knowsEdge("barakmich", "jorgent"),
+				knowsEdge("betawaffle", "robertmeta"), knowsEdge("henrocdotnet", "robertmeta"),
+			},
+			false,
+		},
+		{
+			"inwards", "dennwc", ExpandConfig{Direction: In, Predicates: knowsOnly},
+			map[string]int{"dennwc": 0, "robertmeta": 1, "jorgent": 1},
+			[]Edge{knowsEdge("robertmeta", "dennwc"), knowsEdge("jorgent", "dennwc")},
+			false,
+		},
+		{
+			"both ways", "dennwc", ExpandConfig{Direction: Both, Predicates: knowsOnly},
+			map[string]int{"dennwc": 0, "robertmeta": 1, "jorgent": 1},
+			[]Edge{knowsEdge("dennwc", "robertmeta"), knowsEdge("robertmeta", "dennwc"), knowsEdge("jorgent", "dennwc")},
+			false,
+		},
+		{
+			// robertmeta does not fit in the budget, so the edges to them are left out
+			"node budget", "jorgent", ExpandConfig{Predicates: knowsOnly, MaxDepth: 100, MaxNodes: 3},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1},
+			[]Edge{knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc")},
+			true,
+		},
+		{
+			"budget not reached", "jorgent", ExpandConfig{Predicates: knowsOnly, MaxDepth: 2, MaxNodes: 4},
+			map[string]int{"jorgent": 0, "oren": 1, "dennwc": 1, "robertmeta": 2},
+			[]Edge{
+				knowsEdge("jorgent", "oren"), knowsEdge("jorgent", "dennwc"),
+				knowsEdge("oren", "robertmeta"), knowsEdge("dennwc", "robertmeta"),
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Expand(context.TODO(), store, quad.Raw(tt.start), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(g.Nodes) == 0 || g.Nodes[0] != tt.start {
+				t.Errorf("nodes %v do not start with %s", g.Nodes, tt.start)
+			}
+			if len(g.Nodes) != len(tt.depth) {
+				t.Errorf("nodes %v, want %d", g.Nodes, len(tt.depth))
+			}
+			if !reflect.DeepEqual(g.Depth, tt.depth) {
+				t.Errorf("depth %v, want %v", g.Depth, tt.depth)
+			}
+			if got, want := sortedEdges(g.Edges), sortedEdges(tt.edges); !reflect.DeepEqual(got, want) {
+				t.Errorf("edges %v, want %v", got, want)
+			}
+			if g.Truncated != tt.truncated {
+				t.Errorf("truncated %v, want %v", g.Truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestExpandUnknownDirection(t *testing.T) {
+	store := demoGraph(t)
+	if _, err := Expand(context.TODO(), store, quad.Raw("oren"), ExpandConfig{Direction: "sideways"}); err == nil {
+		t.Error("expected an error for an unknown direction")
+	}
+}
+
+func sortedEdges(edges []Edge) []Edge {
+	sorted := append([]Edge{}, edges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		if a.Predicate != b.Predicate {
+			return a.Predicate < b.Predicate
+		}
+		return a.Object < b.Object
+	})
+	return sorted
+}
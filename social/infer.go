@@ -0,0 +1,140 @@
+package social
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// declarations of the predicate rules, stored in the graph as
+//
+//	drinks_with -type-> symmetric_property
+//	follows_from -inverse_of-> follows_to
+var (
+	predType          = quad.Raw("type")
+	predInverseOf     = quad.Raw("inverse_of")
+	symmetricProperty = quad.Raw("symmetric_property")
+)
+
+// Inference derives the edges that follow from symmetric and inverse predicates at query
+// time, without writing them to the store
+type Inference struct {
+	// Symmetric predicates hold both ways: a -p-> b means b -p-> a
+	Symmetric map[string]bool
+	// Inverse maps a predicate to its inverse: a -p-> b means b -Inverse[p]-> a.
+	// Both directions are in the map.
+	Inverse map[string]string
+}
+
+// LoadInference reads the symmetric_property types and inverse_of declarations of the predicates
+func LoadInference(ctx context.Context, store *cayley.Handle) (*Inference, error) {
+	inf := &Inference{Symmetric: make(map[string]bool), Inverse: make(map[string]string)}
+
+	err := cayley.StartPath(store, symmetricProperty).In(predType).Iterate(ctx).EachValue(nil, func(v quad.Value) {
+		inf.Symmetric[quad.ToString(v)] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := cayley.StartPath(store).Tag("predicate").Out(predInverseOf).Tag("inverse")
+	err = p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		pred, inverse := quad.ToString(m["predicate"]), quad.ToString(m["inverse"])
+		inf.Inverse[pred] = inverse
+		inf.Inverse[inverse] = pred
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inf, nil
+}
+
+// Outs returns the edges from the node, stored and inferred. An inferred edge that is
+// also stored is returned once, as stored.
+func (inf *Inference) Outs(ctx context.Context, store *cayley.Handle, node quad.Value) ([]Edge, error) {
+	return inf.edges(ctx, store, node, true)
+}
+
+// Ins returns the edges to the node, stored and inferred
+func (inf *Inference) Ins(ctx context.Context, store *cayley.Handle, node quad.Value) ([]Edge, error) {
+	return inf.edges(ctx, store, node, false)
+}
+
+func (inf *Inference) edges(ctx context.Context, store *cayley.Handle, node quad.Value, out bool) ([]Edge, error) {
+	steps, _, err := inf.expand(ctx, store, []quad.Value{node}, nil, out, !out)
+	if err != nil {
+		return nil, err
+	}
+	edges := make([]Edge, 0, len(steps))
+	for _, s := range steps {
+		edges = append(edges, s.edge())
+	}
+	return edges, nil
+}
+
+// expand is expand with the inferred steps added. A step that is both stored and inferred is
+// returned once, as stored. A nil Inference adds nothing.
+func (inf *Inference) expand(ctx context.Context, store *cayley.Handle, frontier []quad.Value, predicates []quad.Value, out, in bool) ([]Step, []quad.Value, error) {
+	steps, next, err := expand(ctx, store, frontier, predicates, out, in)
+	if err != nil || inf == nil {
+		return steps, next, err
+	}
+
+	// the stored predicates the rules turn into the asked ones
+	var sources []quad.Value
+	allowed := make(map[string]bool)
+	for _, pred := range predicates {
+		allowed[quad.ToString(pred)] = true
+		if inf.Symmetric[quad.ToString(pred)] {
+			sources = append(sources, pred)
+		}
+		if inverse, ok := inf.Inverse[quad.ToString(pred)]; ok {
+			sources = append(sources, quad.Raw(inverse))
+		}
+	}
+	if len(predicates) > 0 && len(sources) == 0 {
+		return steps, next, nil
+	}
+
+	// the opposite stored steps, which the rules turn around
+	opposite, oppositeNext, err := expand(ctx, store, frontier, sources, in, out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[Step]bool)
+	var unique []Step
+	var uniqueNext []quad.Value
+	add := func(s Step, to quad.Value) {
+		key := s
+		key.Inferred = false
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, s)
+			uniqueNext = append(uniqueNext, to)
+		}
+	}
+
+	// stored steps come first, so they win over the same step inferred
+	for i, s := range steps {
+		add(s, next[i])
+	}
+	for i, s := range opposite {
+		// s follows the quad one way, infer the quad that follows it the other way
+		inferred := Step{From: s.From, To: s.To, Inverse: !s.Inverse, Inferred: true}
+		if inf.Symmetric[s.Predicate] {
+			inferred.Predicate = s.Predicate
+			if len(predicates) == 0 || allowed[inferred.Predicate] {
+				add(inferred, oppositeNext[i])
+			}
+		}
+		if inverse, ok := inf.Inverse[s.Predicate]; ok {
+			inferred.Predicate = inverse
+			if len(predicates) == 0 || allowed[inferred.Predicate] {
+				add(inferred, oppositeNext[i])
+			}
+		}
+	}
+	return unique, uniqueNext, nil
+}
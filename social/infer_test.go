@@ -0,0 +1,165 @@
+package social
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// inferenceGraph returns the demo graph with the predicate rules of cmd/social and a follow
+func inferenceGraph(t *testing.T) (*cayley.Handle, *Inference) {
+	store := demoGraph(t)
+	err := store.AddQuadSet([]quad.Quad{
+		quad.MakeRaw("drinks_with", "type", "symmetric_property", "demo schema"),
+		quad.MakeRaw("works_with", "type", "symmetric_property", "demo schema"),
+		quad.MakeRaw("follows_from", "inverse_of", "follows_to", "demo schema"),
+		quad.MakeRaw("oren", "follows_to", "dennwc", "demo graph"),
+		// stored both ways, inferred edges must not duplicate it
+		quad.MakeRaw("robertmeta", "drinks_with", "barakmich", "demo graph"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inf, err := LoadInference(context.TODO(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, inf
+}
+
+func TestLoadInference(t *testing.T) {
+	_, inf := inferenceGraph(t)
+
+	want := &Inference{
+		Symmetric: map[string]bool{"drinks_with": true, "works_with": true},
+		Inverse:   map[string]string{"follows_from": "follows_to", "follows_to": "follows_from"},
+	}
+	if !reflect.DeepEqual(inf, want) {
+		t.Errorf("inference %+v, want %+v", inf, want)
+	}
+}
+
+func TestInferenceEdges(t *testing.T) {
+	store, inf := inferenceGraph(t)
+
+	tests := []struct {
+		name string
+		node string
+		out  bool
+		want []Edge
+	}{
+		{"symmetric outs", "robertmeta", true, []Edge{
+			{Subject: "robertmeta", Predicate: "drinks_with", Object: "barakmich"},
+			{Subject: "robertmeta", Predicate: "drinks_with", Object: "jorgent", Inferred: true},
+			{Subject: "robertmeta", Predicate: "works_with", Object: "henrocdotnet", Inferred: true},
+		}},
+		{"inverse outs", "dennwc", true, []Edge{
+			{Subject: "dennwc", Predicate: "follows_from", Object: "oren", Inferred: true},
+		}},
+		{"inverse ins", "oren", false, []Edge{
+			{Subject: "dennwc", Predicate: "follows_from", Object: "oren", Inferred: true},
+		}},
+		{"symmetric ins", "jorgent", false, []Edge{
+			{Subject: "robertmeta", Predicate: "drinks_with", Object: "jorgent", Inferred: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Edge
+			var err error
+			if tt.out {
+				got, err = inf.Outs(context.TODO(), store, quad.Raw(tt.node))
+			} else {
+				got, err = inf.Ins(context.TODO(), store, quad.Raw(tt.node))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// knows is no rule, only the other predicates are compared
+			var edges []Edge
+			for _, e := range got {
+				if e.Predicate != "knows" {
+					edges = append(edges, e)
+				}
+			}
+			if got, want := sortedEdges(edges), sortedEdges(tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("edges %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestShortestPathsInference(t *testing.T) {
+	store, inf := inferenceGraph(t)
+	drinksWith := []quad.Value{quad.Raw("drinks_with")}
+
+	tests := []struct {
+		name     string
+		from, to string
+		cfg      PathConfig
+		want     []Path
+	}{
+		{"stored only", "robertmeta", "jorgent", PathConfig{Predicates: drinksWith}, nil},
+		{"symmetric", "jorgent", "barakmich", PathConfig{Predicates: drinksWith, Inference: inf}, []Path{{
+			{From: "jorgent", Predicate: "drinks_with", To: "robertmeta"},
+			{From: "robertmeta", Predicate: "drinks_with", To: "barakmich"},
+		}}},
+		{"inferred step", "robertmeta", "jorgent", PathConfig{Predicates: drinksWith, Inference: inf}, []Path{{
+			{From: "robertmeta", Predicate: "drinks_with", To: "jorgent", Inferred: true},
+		}}},
+		{"inverse", "dennwc", "oren", PathConfig{Predicates: []quad.Value{quad.Raw("follows_from")}, Inference: inf}, []Path{{
+			{From: "dennwc", Predicate: "follows_from", To: "oren", Inferred: true},
+		}}},
+		{"rules need the predicate", "dennwc", "oren", PathConfig{Inference: inf}, []Path{{
+			{From: "dennwc", Predicate: "knows", To: "robertmeta"},
+			{From: "robertmeta", Predicate: "knows", To: "oren"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShortestPaths(context.TODO(), store, quad.Raw(tt.from), quad.Raw(tt.to), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandInference(t *testing.T) {
+	store, inf := inferenceGraph(t)
+
+	cfg := ExpandConfig{Predicates: []quad.Value{quad.Raw("drinks_with")}, MaxDepth: 2, Inference: inf}
+	g, err := Expand(context.TODO(), store, quad.Raw("barakmich"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDepth := map[string]int{"barakmich": 0, "robertmeta": 1, "jorgent": 2}
+	if !reflect.DeepEqual(g.Depth, wantDepth) {
+		t.Errorf("depth %v, want %v", g.Depth, wantDepth)
+	}
+	// barakmich -drinks_with-> robertmeta is stored and inferred, it is kept once as stored
+	wantEdges := []Edge{
+		{Subject: "barakmich", Predicate: "drinks_with", Object: "robertmeta"},
+		{Subject: "robertmeta", Predicate: "drinks_with", Object: "barakmich"},
+		{Subject: "robertmeta", Predicate: "drinks_with", Object: "jorgent", Inferred: true},
+	}
+	if got, want := sortedEdges(g.Edges), sortedEdges(wantEdges); !reflect.DeepEqual(got, want) {
+		t.Errorf("edges %v, want %v", got, want)
+	}
+
+	// without the rules jorgent is only reached backwards
+	cfg.Inference = nil
+	g, err = Expand(context.TODO(), store, quad.Raw("barakmich"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Depth["jorgent"]; ok {
+		t.Errorf("nodes %v, want no jorgent without inference", g.Nodes)
+	}
+}
@@ -0,0 +1,172 @@
+package social
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// DefaultMaxDepth is the number of hops ShortestPaths searches when PathConfig.MaxDepth is not set
+const DefaultMaxDepth = 6
+
+// PathConfig configures ShortestPaths
+type PathConfig struct {
+	// Predicates are the edges that may be followed, defaults to knows
+	Predicates []quad.Value
+	// MaxDepth is the longest path searched for, defaults to DefaultMaxDepth
+	MaxDepth int
+	// All returns every shortest path instead of one
+	All bool
+	// Undirected also follows the edges backwards
+	Undirected bool
+	// Inference adds the edges that follow from symmetric and inverse predicates, see LoadInference
+	Inference *Inference
+}
+
+// Step is one edge of a path. Inverse is true when the edge was followed backwards,
+// the quad is then To -Predicate-> From. Inferred is true when the quad is not stored
+// but follows from the predicate rules.
+type Step struct {
+	From      string `json:"from"`
+	Predicate string `json:"predicate"`
+	To        string `json:"to"`
+	Inverse   bool   `json:"inverse,omitempty"`
+	Inferred  bool   `json:"inferred,omitempty"`
+}
+
+// Path is a chain of steps, its length is the degree of separation
+type Path []Step
+
+// ShortestPaths returns the shortest chains of edges from one person to another, found with
+// a breadth first search. It returns no paths when they are not connected within
+// PathConfig.MaxDepth, and an empty path when from is to.
+//
+//	henrocdotnet -knows-> robertmeta -knows-> barakmich -knows-> jorgent
+func ShortestPaths(ctx context.Context, store *cayley.Handle, from, to quad.Value, cfg PathConfig) ([]Path, error) {
+	if len(cfg.Predicates) == 0 {
+		cfg.Predicates = []quad.Value{predKnows}
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = DefaultMaxDepth
+	}
+
+	start, target := quad.ToString(from), quad.ToString(to)
+	if start == target {
+		return []Path{{}}, nil
+	}
+
+	depth := map[string]int{start: 0}
+	// parents are the steps that reach a node at its depth
+	parents := make(map[string][]Step)
+	frontier := []quad.Value{from}
+
+	for d := 1; d <= cfg.MaxDepth && len(frontier) > 0; d++ {
+		steps, next, err := cfg.Inference.expand(ctx, store, frontier, cfg.Predicates, true, cfg.Undirected)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for i, s := range steps {
+			seen, ok := depth[s.To]
+			if !ok {
+				depth[s.To] = d
+				frontier = append(frontier, next[i])
+			} else if seen != d {
+				continue
+			}
+			parents[s.To] = append(parents[s.To], s)
+		}
+
+		if _, ok := depth[target]; ok {
+			return pathsTo(target, start, parents, cfg.All), nil
+		}
+	}
+	return nil, nil
+}
+
+// expand returns the steps along the predicates (all when there are none) from the nodes
+// of the frontier, outwards and/or inwards, with the nodes they lead to
+func expand(ctx context.Context, store *cayley.Handle, frontier []quad.Value, predicates []quad.Value, out, in bool) ([]Step, []quad.Value, error) {
+	var steps []Step
+	var next []quad.Value
+	preds := interfaces(predicates)
+
+	if out {
+		p := cayley.StartPath(store, frontier...).Tag("from").OutWithTags([]string{"predicate"}, preds...).Tag("to")
+		err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+			steps = append(steps, Step{From: quad.ToString(m["from"]), Predicate: quad.ToString(m["predicate"]), To: quad.ToString(m["to"])})
+			next = append(next, m["to"])
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if !in {
+		return steps, next, nil
+	}
+
+	p := cayley.StartPath(store, frontier...).Tag("from").InWithTags([]string{"predicate"}, preds...).Tag("to")
+	err := p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		steps = append(steps, Step{From: quad.ToString(m["from"]), Predicate: quad.ToString(m["predicate"]), To: quad.ToString(m["to"]), Inverse: true})
+		next = append(next, m["to"])
+	})
+	return steps, next, err
+}
+
+// pathsTo walks the parents back from node to start. Without all only the first path,
+// in the order of the steps, is returned.
+func pathsTo(node, start string, parents map[string][]Step, all bool) []Path {
+	if node == start {
+		return []Path{{}}
+	}
+
+	steps := uniqueSteps(parents[node])
+	var paths []Path
+	for _, s := range steps {
+		for _, p := range pathsTo(s.From, start, parents, all) {
+			paths = append(paths, append(append(Path{}, p...), s))
+			if !all {
+				return paths
+			}
+		}
+	}
+	return paths
+}
+
+// uniqueSteps sorts the steps and drops duplicates, which the store returns for repeated quads
+func uniqueSteps(steps []Step) []Step {
+	sort.Slice(steps, func(i, j int) bool {
+		a, b := steps[i], steps[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Predicate != b.Predicate {
+			return a.Predicate < b.Predicate
+		}
+		if a.Inverse != b.Inverse {
+			return !a.Inverse
+		}
+		return !a.Inferred && b.Inferred
+	})
+
+	unique := steps[:0]
+	for i, s := range steps {
+		if i > 0 && s == steps[i-1] {
+			continue
+		}
+		unique = append(unique, s)
+	}
+	return unique
+}
+
+// interfaces converts values for the variadic interface{} arguments of the path steps
+func interfaces(values []quad.Value) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
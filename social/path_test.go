@@ -0,0 +1,132 @@
+package social
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+// demoGraph returns a memory store with the people of cmd/social and how they know each other
+func demoGraph(t *testing.T) *cayley.Handle {
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddQuadSet([]quad.Quad{
+		quad.MakeRaw("barakmich", "drinks_with", "robertmeta", "demo graph"),
+		quad.MakeRaw("barakmich", "knows", "robertmeta", "demo graph"),
+		quad.MakeRaw("barakmich", "knows", "jorgent", "demo graph"),
+		quad.MakeRaw("betawaffle", "knows", "robertmeta", "demo graph"),
+		quad.MakeRaw("dennwc", "knows", "robertmeta", "demo graph"),
+		quad.MakeRaw("henrocdotnet", "knows", "robertmeta", "demo graph"),
+		quad.MakeRaw("henrocdotnet", "works_with", "robertmeta", "demo graph"),
+		quad.MakeRaw("oren", "knows", "robertmeta", "demo graph"),
+		quad.MakeRaw("oren", "makes_talks_with", "robertmeta", "demo graph"),
+		quad.MakeRaw("robertmeta", "knows", "barakmich", "demo graph"),
+		quad.MakeRaw("robertmeta", "knows", "betawaffle", "demo graph"),
+		quad.MakeRaw("robertmeta", "knows", "dennwc", "demo graph"),
+		quad.MakeRaw("robertmeta", "knows", "henrocdotnet", "demo graph"),
+		quad.MakeRaw("robertmeta", "knows", "oren", "demo graph"),
+		quad.MakeRaw("jorgent", "knows", "oren", "demo graph"),
+		quad.MakeRaw("jorgent", "knows", "dennwc", "demo graph"),
+		quad.MakeRaw("jorgent", "drinks_with", "robertmeta", "demo graph"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func knows(from, to string) Step {
+	return Step{From: from, Predicate: "knows", To: to}
+}
+
+func TestShortestPaths(t *testing.T) {
+	store := demoGraph(t)
+
+	tests := []struct {
+		name     string
+		from, to string
+		cfg      PathConfig
+		want     []Path
+	}{
+		{"same person", "oren", "oren", PathConfig{}, []Path{{}}},
+		{"one path", "henrocdotnet", "jorgent", PathConfig{All: true}, []Path{
+			{knows("henrocdotnet", "robertmeta"), knows("robertmeta", "barakmich"), knows("barakmich", "jorgent")},
+		}},
+		{"first of two", "jorgent", "robertmeta", PathConfig{}, []Path{
+			{knows("jorgent", "dennwc"), knows("dennwc", "robertmeta")},
+		}},
+		{"all shortest", "jorgent", "robertmeta", PathConfig{All: true}, []Path{
+			{knows("jorgent", "dennwc"), knows("dennwc", "robertmeta")},
+			{knows("jorgent", "oren"), knows("oren", "robertmeta")},
+		}},
+		{"too deep", "henrocdotnet", "jorgent", PathConfig{MaxDepth: 2}, nil},
+		{"deep enough", "henrocdotnet", "jorgent", PathConfig{MaxDepth: 3}, []Path{
+			{knows("henrocdotnet", "robertmeta"), knows("robertmeta", "barakmich"), knows("barakmich", "jorgent")},
+		}},
+		{"directed", "dennwc", "jorgent", PathConfig{}, []Path{
+			{knows("dennwc", "robertmeta"), knows("robertmeta", "barakmich"), knows("barakmich", "jorgent")},
+		}},
+		{"undirected", "dennwc", "jorgent", PathConfig{Undirected: true}, []Path{
+			{{From: "dennwc", Predicate: "knows", To: "jorgent", Inverse: true}},
+		}},
+		{"other predicates", "barakmich", "robertmeta", PathConfig{Predicates: []quad.Value{quad.Raw("drinks_with")}}, []Path{
+			{{From: "barakmich", Predicate: "drinks_with", To: "robertmeta"}},
+		}},
+		// robertmeta and the people they know form cycles, every person is visited once
+		// so the search ends long before the maximal depth
+		{"not connected", "betawaffle", "nobody", PathConfig{MaxDepth: 100}, nil},
+		{"not followed backwards", "robertmeta", "jorgent", PathConfig{Predicates: []quad.Value{quad.Raw("drinks_with")}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShortestPaths(context.TODO(), store, quad.Raw(tt.from), quad.Raw(tt.to), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortestPathsUndirectedAll(t *testing.T) {
+	store := demoGraph(t)
+
+	// henrocdotnet and robertmeta know each other, and robertmeta and barakmich, dennwc and
+	// oren too, so every hop but the last one can be taken both ways
+	paths, err := ShortestPaths(context.TODO(), store, quad.Raw("henrocdotnet"), quad.Raw("jorgent"), PathConfig{All: true, Undirected: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2*3*2 {
+		t.Errorf("got %d paths, want 12", len(paths))
+	}
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if len(p) != 3 || p[0].From != "henrocdotnet" || p[2].To != "jorgent" {
+			t.Errorf("unexpected path %v", p)
+		}
+		// barakmich knows jorgent, the others are known by jorgent
+		if last := p[2]; last.Inverse != (last.From != "barakmich") {
+			t.Errorf("unexpected last step %v", last)
+		}
+		key := ""
+		for _, s := range p {
+			key += s.From + ">" + s.To
+			if s.Inverse {
+				key += "'"
+			}
+			key += " "
+		}
+		if seen[key] {
+			t.Errorf("duplicate path %v", p)
+		}
+		seen[key] = true
+	}
+}
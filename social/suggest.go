@@ -0,0 +1,77 @@
+// Package social answers questions about the people in the social demo graph, like
+// who you may know.
+package social
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+)
+
+var predKnows = quad.Raw("knows")
+
+// Suggestion is a person you may know, through the friends you have in common
+type Suggestion struct {
+	Person string `json:"person"`
+	// MutualFriends is the number of people you know who know the suggested person
+	MutualFriends int `json:"mutual_friends"`
+	// Mutual are the mutual friends, sorted
+	Mutual []string `json:"mutual"`
+}
+
+// PeopleYouMayKnow suggests the friends of the person's friends, ranked by the number of
+// mutual friends. Knowing someone counts both ways, whoever of the two stored it. The person
+// and the people they already know are left out. A limit of 0 returns all suggestions.
+// The predicate rules of LoadInference are not applied, only knows edges count.
+//
+//	person <-knows-> friend <-knows-> suggestion
+func PeopleYouMayKnow(ctx context.Context, store *cayley.Handle, person quad.Value, limit int) ([]Suggestion, error) {
+	friends := cayley.StartPath(store, person).Both(predKnows)
+
+	contacts := map[string]bool{quad.ToString(person): true}
+	err := friends.Iterate(ctx).EachValue(nil, func(v quad.Value) {
+		contacts[quad.ToString(v)] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mutual := make(map[string]map[string]bool)
+	p := friends.Tag("friend").Both(predKnows).Tag("candidate")
+	err = p.Iterate(ctx).TagValues(nil, func(m map[string]quad.Value) {
+		candidate := quad.ToString(m["candidate"])
+		if contacts[candidate] {
+			return
+		}
+		if mutual[candidate] == nil {
+			mutual[candidate] = make(map[string]bool)
+		}
+		mutual[candidate][quad.ToString(m["friend"])] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(mutual))
+	for candidate, friends := range mutual {
+		s := Suggestion{Person: candidate, MutualFriends: len(friends)}
+		for f := range friends {
+			s.Mutual = append(s.Mutual, f)
+		}
+		sort.Strings(s.Mutual)
+		suggestions = append(suggestions, s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].MutualFriends != suggestions[j].MutualFriends {
+			return suggestions[i].MutualFriends > suggestions[j].MutualFriends
+		}
+		return suggestions[i].Person < suggestions[j].Person
+	})
+
+	if limit > 0 && limit < len(suggestions) {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,47 @@
+package social
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cayleygraph/cayley/quad"
+)
+
+func TestPeopleYouMayKnow(t *testing.T) {
+	store := demoGraph(t)
+
+	tests := []struct {
+		person string
+		limit  int
+		want   []Suggestion
+	}{
+		// barakmich knows jorgent, who only knows oren and dennwc the other way around
+		{"barakmich", 0, []Suggestion{
+			{Person: "dennwc", MutualFriends: 2, Mutual: []string{"jorgent", "robertmeta"}},
+			{Person: "oren", MutualFriends: 2, Mutual: []string{"jorgent", "robertmeta"}},
+			{Person: "betawaffle", MutualFriends: 1, Mutual: []string{"robertmeta"}},
+			{Person: "henrocdotnet", MutualFriends: 1, Mutual: []string{"robertmeta"}},
+		}},
+		// jorgent is known by barakmich without knowing them back
+		{"jorgent", 0, []Suggestion{
+			{Person: "robertmeta", MutualFriends: 3, Mutual: []string{"barakmich", "dennwc", "oren"}},
+		}},
+		{"betawaffle", 2, []Suggestion{
+			{Person: "barakmich", MutualFriends: 1, Mutual: []string{"robertmeta"}},
+			{Person: "dennwc", MutualFriends: 1, Mutual: []string{"robertmeta"}},
+		}},
+		{"nobody", 0, []Suggestion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.person, func(t *testing.T) {
+			got, err := PeopleYouMayKnow(context.TODO(), store, quad.Raw(tt.person), tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggestions %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package vocab names the predicates and classes of the recommendations graph in one
+// place, with the helpers the demo packages use to read and write values.
+package vocab
+
+import "github.com/cayleygraph/cayley/quad"
+
+// predicates of the catalog, the clients and their purchases
+var (
+	Type      = quad.IRI("type")
+	Label     = quad.IRI("label")
+	Desc      = quad.IRI("desc")
+	Firstname = quad.IRI("firstname")
+
+	InGroup    = quad.IRI("in_group")
+	Price      = quad.IRI("price")
+	Consumable = quad.IRI("consumable")
+	Added      = quad.IRI("added")
+
+	Stock        = quad.IRI("stock")
+	Available    = quad.IRI("available")
+	Discontinued = quad.IRI("discontinued")
+
+	// a purchase is stored as a node of its own:
+	//
+	//	client -has_purchase-> purchase -purchase_of-> product
+	//	                       purchase -at-> time, -quantity-> int, -unit_price-> float
+	HasPurchase = quad.IRI("has_purchase")
+	PurchaseOf  = quad.IRI("purchase_of")
+	At          = quad.IRI("at")
+	Quantity    = quad.IRI("quantity")
+	UnitPrice   = quad.IRI("unit_price")
+)
+
+// predicates that describe the classes
+var (
+	HasProperty         = quad.IRI("hasProperty")
+	HasOptionalProperty = quad.IRI("hasOptionalProperty")
+	Range               = quad.IRI("range")
+)
+
+// classes, their IRIs are not used as predicates
+var (
+	ClassClass   = quad.IRI("class")
+	ClassProduct = quad.IRI("product")
+)
+
+// String returns the bare string of an IRI or string value, without the <> or ""
+// that quad.Value.String() adds
+func String(v quad.Value) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case quad.IRI:
+		return string(t)
+	case quad.String:
+		return string(t)
+	default:
+		return v.String()
+	}
+}
+
+// Labeled returns a writer that writes all quads under the label
+func Labeled(w quad.Writer, label string) quad.Writer {
+	return labelWriter{w: w, label: quad.String(label)}
+}
+
+type labelWriter struct {
+	w     quad.Writer
+	label quad.Value
+}
+
+func (lw labelWriter) WriteQuad(q quad.Quad) error {
+	q.Label = lw.label
+	return lw.w.WriteQuad(q)
+}
+
+func (lw labelWriter) WriteQuads(buf []quad.Quad) (int, error) {
+	for i, q := range buf {
+		if err := lw.WriteQuad(q); err != nil {
+			return i, err
+		}
+	}
+	return len(buf), nil
+}